@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The security scheme types and http schemes to authenticate with
+const (
+	SecuritySchemeHttp          = "http"
+	SecuritySchemeApiKey        = "apiKey"
+	SecuritySchemeOAuth2        = "oauth2"
+	SecuritySchemeOpenIdConnect = "openIdConnect"
+	HttpSchemeBasic             = "basic"
+	HttpSchemeBearer            = "bearer"
+)
+
+// The prefix of credential environment variables, e.g. "APING_BEARERAUTH_TOKEN"
+const CredentialEnvPrefix = "APING_"
+
+// Matching pattern for characters not allowed in environment variable names
+var regExEnvNamePattern = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// The security schemes and default requirements of the spec, with the credentials by scheme name
+var (
+	securitySchemes map[string]*openapi3.SecuritySchemeRef
+	globalSecurity  openapi3.SecurityRequirements
+	credentials     = make(map[string]*Credential)
+)
+
+// The credential of a security scheme, e.g. a basic username and password, a bearer token or an api key
+type Credential struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Key      string `json:"key,omitempty"`
+	// OAuth2 clients to fetch tokens with
+	ClientId     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	TokenUrl     string `json:"tokenUrl,omitempty"`
+}
+
+// Parse the security schemes and any credentials of the secrets file, environment and flag (in ascending precedence)
+func parseAuth(swagger *openapi3.Swagger) {
+	securitySchemes = swagger.Components.SecuritySchemes
+	globalSecurity = swagger.Security
+
+	if secretsFlag != nil && *secretsFlag != "" {
+		secrets := make(map[string]*Credential)
+		checkFatalError(loadConfig(*secretsFlag, &secrets))
+		mergeCredentials(secrets)
+	}
+	for name := range securitySchemes {
+		mergeCredential(name, getEnvCredential(name))
+	}
+	if authFlag != nil && *authFlag != "" {
+		auth := make(map[string]*Credential)
+		checkFatalError(json.Unmarshal([]byte(*authFlag), &auth))
+		mergeCredentials(auth)
+	}
+}
+
+// Merge the credentials by scheme name into the current ones
+func mergeCredentials(result map[string]*Credential) {
+	for name, credential := range result {
+		mergeCredential(name, credential)
+	}
+}
+
+// Merge all given fields of a credential into the current one of the scheme
+func mergeCredential(name string, credential *Credential) {
+	if credential == nil {
+		return
+	}
+	current, ok := credentials[name]
+	if !ok {
+		current = &Credential{}
+		credentials[name] = current
+	}
+	if credential.Username != "" {
+		current.Username = credential.Username
+	}
+	if credential.Password != "" {
+		current.Password = credential.Password
+	}
+	if credential.Token != "" {
+		current.Token = credential.Token
+	}
+	if credential.Key != "" {
+		current.Key = credential.Key
+	}
+	if credential.ClientId != "" {
+		current.ClientId = credential.ClientId
+	}
+	if credential.ClientSecret != "" {
+		current.ClientSecret = credential.ClientSecret
+	}
+	if credential.TokenUrl != "" {
+		current.TokenUrl = credential.TokenUrl
+	}
+}
+
+// Get the credential of a scheme from the environment, e.g. "APING_BASICAUTH_USERNAME" for "basicAuth"
+func getEnvCredential(name string) *Credential {
+	prefix := CredentialEnvPrefix + regExEnvNamePattern.ReplaceAllString(strings.ToUpper(name), "_") + "_"
+	return &Credential{
+		Username:     os.Getenv(prefix + "USERNAME"),
+		Password:     os.Getenv(prefix + "PASSWORD"),
+		Token:        os.Getenv(prefix + "TOKEN"),
+		Key:          os.Getenv(prefix + "KEY"),
+		ClientId:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		TokenUrl:     os.Getenv(prefix + "TOKEN_URL"),
+	}
+}
+
+// Apply the credentials of the first satisfiable security requirement of the operation (or the spec) to the request.
+// Returns any query pairs to add
+func applySecurity(request *Request, operation *openapi3.Operation) []string {
+	requirements := globalSecurity
+	if operation.Security != nil {
+		requirements = *operation.Security
+	}
+
+	query := make([]string, 0)
+	for _, requirement := range getSortedRequirements(requirements) {
+		if !isSatisfiable(requirement) {
+			continue
+		}
+		for _, name := range getSortedSchemeNames(requirement) {
+			scheme := securitySchemes[name].Value
+			credential := credentials[name]
+			switch scheme.Type {
+			case SecuritySchemeHttp:
+				if strings.EqualFold(scheme.Scheme, HttpSchemeBasic) {
+					request.Headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credential.Username+":"+credential.Password))
+				} else {
+					// Other schemes than bearer are sent as given, e.g. "Token abc"
+					authorization := scheme.Scheme
+					if strings.EqualFold(authorization, HttpSchemeBearer) {
+						authorization = "Bearer"
+					}
+					request.Headers["Authorization"] = authorization + " " + credential.Token
+				}
+			case SecuritySchemeApiKey:
+				switch scheme.In {
+				case openapi3.ParameterInHeader:
+					request.Headers[scheme.Name] = getApiKey(credential)
+				case openapi3.ParameterInQuery:
+					query = append(query, url.QueryEscape(scheme.Name)+"="+url.QueryEscape(getApiKey(credential)))
+				case openapi3.ParameterInCookie:
+					request.Cookies[scheme.Name] = getApiKey(credential)
+				}
+			case SecuritySchemeOAuth2, SecuritySchemeOpenIdConnect:
+				token := credential.Token
+				if token == "" {
+					// Without a token of the endpoint the request is sent unauthenticated
+					token, _ = getOAuthToken(name, scheme, credential, requirement[name])
+				}
+				if token != "" {
+					request.Headers["Authorization"] = "Bearer " + token
+				}
+			}
+		}
+		break
+	}
+	return query
+}
+
+// Order the requirements by preference, i.e. an empty (optional) one last
+func getSortedRequirements(requirements openapi3.SecurityRequirements) openapi3.SecurityRequirements {
+	sorted := make(openapi3.SecurityRequirements, 0, len(requirements))
+	for _, requirement := range requirements {
+		if len(requirement) > 0 {
+			sorted = append(sorted, requirement)
+		}
+	}
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			sorted = append(sorted, requirement)
+		}
+	}
+	return sorted
+}
+
+// Check if all schemes of the requirement are known and have credentials
+func isSatisfiable(requirement openapi3.SecurityRequirement) bool {
+	for name := range requirement {
+		schemeRef, ok := securitySchemes[name]
+		if !ok || schemeRef == nil || schemeRef.Value == nil {
+			return false
+		}
+		credential, ok := credentials[name]
+		if !ok {
+			return false
+		}
+		switch schemeRef.Value.Type {
+		case SecuritySchemeHttp:
+			if strings.EqualFold(schemeRef.Value.Scheme, HttpSchemeBasic) {
+				if credential.Username == "" {
+					return false
+				}
+			} else if credential.Token == "" {
+				return false
+			}
+		case SecuritySchemeApiKey:
+			if getApiKey(credential) == "" {
+				return false
+			}
+		case SecuritySchemeOAuth2, SecuritySchemeOpenIdConnect:
+			if credential.Token == "" && !isOAuthFetchable(schemeRef.Value, credential) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Get the scheme names of a requirement sorted for a stable order
+func getSortedSchemeNames(requirement openapi3.SecurityRequirement) []string {
+	names := make([]string, 0, len(requirement))
+	for name := range requirement {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The api key, falling back to a token
+func getApiKey(credential *Credential) string {
+	if credential.Key != "" {
+		return credential.Key
+	}
+	return credential.Token
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jedib0t/go-pretty/progress"
+	"sync/atomic"
+	"time"
+)
+
+// The budget of the run, if any, instead of a number of loops
+var budget *Budget
+
+// Progress units of elapsed milliseconds, e.g. "1m2s"
+var unitsDuration = progress.Units{
+	Formatter: func(value int64) string {
+		return (time.Duration(value) * time.Millisecond).Round(time.Second).String()
+	},
+}
+
+// A duration and/or request count to keep pinging for, whichever is exhausted first
+type Budget struct {
+	Duration time.Duration
+	Requests int64
+	start    time.Time
+	sent     int64
+	// The progress of the elapsed time and the sent requests
+	durationTracker *progress.Tracker
+	requestsTracker *progress.Tracker
+	done            chan struct{}
+}
+
+// Parse any given duration or request count budget
+func parseBudget() {
+	if *durationFlag < 0 || *requestsFlag < 0 {
+		checkFatalError(fmt.Errorf("[aPing] The duration and requests cannot be negative"))
+	}
+	if *durationFlag == 0 && *requestsFlag == 0 {
+		return
+	}
+	budget = &Budget{Duration: *durationFlag, Requests: *requestsFlag}
+}
+
+// Start the budget now, tracking its progress
+func (budget *Budget) begin() {
+	budget.start = time.Now()
+	budget.done = make(chan struct{})
+	beginStages()
+	if budget.Duration > 0 {
+		budget.durationTracker = &progress.Tracker{Message: fmt.Sprintf("Pinging for %s", budget.Duration), Total: budget.Duration.Milliseconds(), Units: unitsDuration}
+		progressWriter.AppendTracker(budget.durationTracker)
+		go budget.trackDuration()
+	}
+	if budget.Requests > 0 {
+		budget.requestsTracker = &progress.Tracker{Message: fmt.Sprintf("Pinging %d requests", budget.Requests), Total: budget.Requests, Units: progress.UnitsDefault}
+		progressWriter.AppendTracker(budget.requestsTracker)
+	}
+}
+
+// Update the elapsed time until the budget ends
+func (budget *Budget) trackDuration() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-budget.done:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if elapsed := now.Sub(budget.start); elapsed < budget.Duration {
+				budget.durationTracker.SetValue(elapsed.Milliseconds())
+			}
+			if stages != nil {
+				trackStages(now)
+			}
+		}
+	}
+}
+
+// End the budget and its progress, some requests may not have been sent
+func (budget *Budget) end() {
+	close(budget.done)
+	endStages()
+	if budget.durationTracker != nil {
+		budget.durationTracker.MarkAsDone()
+	}
+	if budget.requestsTracker != nil {
+		budget.requestsTracker.MarkAsDone()
+	}
+}
+
+// Count the progress trackers of the budget
+func (budget *Budget) countTrackers() int {
+	count := len(stages)
+	if budget.Duration > 0 {
+		count++
+	}
+	if budget.Requests > 0 {
+		count++
+	}
+	return count
+}
+
+// Take one request from the budget, if neither the duration nor the requests are exhausted.
+// Without a budget there is no limit
+func (budget *Budget) take() bool {
+	return budget.takeAt(time.Now())
+}
+
+// Take one request starting at the given time from the budget, e.g. the intended start of a rate
+func (budget *Budget) takeAt(start time.Time) bool {
+	if budget == nil {
+		return true
+	}
+	if budget.Duration > 0 && start.Sub(budget.start) >= budget.Duration {
+		return false
+	}
+	if budget.Requests > 0 && atomic.AddInt64(&budget.sent, 1) > budget.Requests {
+		return false
+	}
+	return true
+}
+
+// Check if the duration or requests are exhausted. Without a budget there is a single round only
+func (budget *Budget) exhausted() bool {
+	if budget == nil {
+		return true
+	}
+	if budget.Duration > 0 && time.Since(budget.start) >= budget.Duration {
+		return true
+	}
+	return budget.Requests > 0 && atomic.LoadInt64(&budget.sent) >= budget.Requests
+}
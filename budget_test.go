@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBudgetTakeAt(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name     string
+		budget   *Budget
+		at       time.Duration
+		takes    int
+		expected int
+	}{
+		{"no budget", nil, 0, 5, 5},
+		{"requests", &Budget{Requests: 3, start: start}, 0, 5, 3},
+		{"within duration", &Budget{Duration: time.Minute, start: start}, 30 * time.Second, 5, 5},
+		{"after duration", &Budget{Duration: time.Minute, start: start}, time.Minute, 5, 0},
+		{"intended within duration", &Budget{Duration: time.Minute, start: start.Add(-2 * time.Minute)}, 59 * time.Second, 2, 2},
+		{"requests within duration", &Budget{Duration: time.Minute, Requests: 2, start: start}, time.Second, 5, 2},
+		{"requests after duration", &Budget{Duration: time.Minute, Requests: 2, start: start}, 2 * time.Minute, 5, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			at := start.Add(test.at)
+			if test.budget != nil {
+				at = test.budget.start.Add(test.at)
+			}
+			taken := 0
+			for i := 0; i < test.takes; i++ {
+				if test.budget.takeAt(at) {
+					taken++
+				}
+			}
+			if taken != test.expected {
+				t.Errorf("took %d, expected %d", taken, test.expected)
+			}
+		})
+	}
+}
+
+func TestBudgetTakeConcurrently(t *testing.T) {
+	budget := &Budget{Requests: 100, start: time.Now()}
+	var waitGroup sync.WaitGroup
+	var mutex sync.Mutex
+	taken := 0
+	for worker := 0; worker < 10; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for i := 0; i < 50; i++ {
+				if budget.take() {
+					mutex.Lock()
+					taken++
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	waitGroup.Wait()
+	if taken != 100 {
+		t.Errorf("took %d, expected 100", taken)
+	}
+}
+
+func TestBudgetExhausted(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   *Budget
+		takes    int
+		expected bool
+	}{
+		{"no budget", nil, 0, true},
+		{"requests left", &Budget{Requests: 3, start: time.Now()}, 2, false},
+		{"requests taken", &Budget{Requests: 3, start: time.Now()}, 3, true},
+		{"requests overdrawn", &Budget{Requests: 3, start: time.Now()}, 5, true},
+		{"duration left", &Budget{Duration: time.Minute, start: time.Now()}, 10, false},
+		{"duration passed", &Budget{Duration: time.Minute, start: time.Now().Add(-time.Minute)}, 0, true},
+		{"duration passed with requests left", &Budget{Duration: time.Minute, Requests: 10, start: time.Now().Add(-2 * time.Minute)}, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < test.takes; i++ {
+				test.budget.take()
+			}
+			if actual := test.budget.exhausted(); actual != test.expected {
+				t.Errorf("exhausted %t, expected %t", actual, test.expected)
+			}
+		})
+	}
+}
@@ -8,8 +8,11 @@ import (
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/jedib0t/go-pretty/progress"
 	"log"
+	"math/rand"
+	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,19 +23,42 @@ var QueryMethods []string
 
 // Define the possible command line arguments
 var (
-	inputFlag     = flag.String("input", "", "*The path/url to the Swagger/OpenAPI 3.0 input source")
-	basePathFlag  = flag.String("base", "", "The base url to query")
-	outputFlag    = flag.String("out", "console", "The output format. Options: console, csv, html, md, json")
-	headerFlag    = flag.String("header", "{}", "Pass a custom header as JSON string, e.g. '{\"Authorization\": \"Bearer TOKEN\"}'")
-	workerFlag    = flag.Int("worker", 1, "The amount of parallel workers to use")
-	timeoutFlag   = flag.Int("timeout", 5, "The timeout in seconds per request")
-	loopFlag      = flag.Int("loop", 1, "How often to loop through all calls")
-	responseFlag  = flag.Bool("response", false, "Include the response body in the output")
-	methodsFlag   = flag.String("methods", "[\"GET\",\"POST\"]", "An array of query methods to include, e.g. '[\"GET\", \"POST\"]'")
-	filterFlag    = flag.String("filter", "", "A regular expression to filter matching paths. Only will be pinged!")
-	thresholdFlag = flag.Int("threshold", -1, "Only collect pings above this response threshold in milliseconds")
+	inputFlag            = flag.String("input", "", "*The path/url to the Swagger/OpenAPI 3.0 input source (JSON or YAML), \"-\" for stdin")
+	basePathFlag         = flag.String("base", "", "The base url to query")
+	outputFlag           = flag.String("out", "console", "A comma separated list of output formats, each with an optional file, e.g. 'console,json=reports/{{TIMESTAMP}}.json'. Options: console, csv, html, md, json, junit")
+	headerFlag           = flag.String("header", "{}", "Pass a custom header as JSON string, e.g. '{\"Authorization\": \"Bearer TOKEN\"}'")
+	workerFlag           = flag.Int("worker", 1, "The amount of parallel workers to use")
+	timeoutFlag          = flag.Int("timeout", 5, "The timeout in seconds per request")
+	loopFlag             = flag.Int("loop", 1, "How often to loop through all calls")
+	warmupFlag           = flag.String("warmup", "", "Rounds (e.g. 2) or a duration (e.g. 30s) to ping before the run, excluded from the statistics except for errors")
+	durationFlag         = flag.Duration("duration", 0, "Keep pinging for this long instead of a number of loops, e.g. 5m")
+	weightsFlag          = flag.String("weights", "", "A JSON/YAML file with traffic mix weights by operationId, tag or path pattern for duration/count-based runs")
+	stagesFlag           = flag.String("stages", "", "A load profile ramping the workers (or rate) as JSON/YAML file or list of duration:target, e.g. '2m:50,5m:50,1m:0'")
+	rateFlag             = flag.Float64("rate", 0, "Start this many requests per second on a fixed timeline, independent of the response times (open model)")
+	ratesFlag            = flag.String("rates", "", "Own requests per second by operationId or operation key as JSON string, e.g. '{\"getOrder\": 5}'")
+	requestsFlag         = flag.Int64("requests", 0, "Keep pinging until this many requests are sent instead of a number of loops")
+	responseFlag         = flag.Bool("response", false, "Include the response body in the output")
+	methodsFlag          = flag.String("methods", "[\"GET\",\"POST\"]", "An array of query methods to include, e.g. '[\"GET\", \"POST\"]'")
+	filterFlag           = flag.String("filter", "", "A regular expression to filter matching paths. Only will be pinged!")
+	thresholdFlag        = flag.Int("threshold", -1, "Only collect pings above this response threshold in milliseconds")
+	paramsFlag           = flag.String("params", "", "A JSON/YAML file with fixture values by parameter name, operationId or path pattern")
+	seedFlag             = flag.Int64("seed", 0, "The seed for random parameters and bodies to replay a run, 0 for a random seed")
+	authFlag             = flag.String("auth", "", "Pass credentials by security scheme name as JSON string, e.g. '{\"bearerAuth\": {\"token\": \"TOKEN\"}}'")
+	secretsFlag          = flag.String("secrets", "", "A JSON/YAML file with credentials by security scheme name")
+	loginFlag            = flag.String("login", "", "A JSON/YAML file with a request to log in each worker before the run")
+	jarFlag              = flag.Bool("jar", false, "Keep the response cookies per worker, like independent users (always with login)")
+	validateFlag         = flag.Bool("validate", false, "Validate the status code, content type and body of all responses against the spec")
+	violationsFlag       = flag.Int("violations", 5, "The maximum violation messages per operation to output with validate")
+	maxErrorRateFlag     = flag.Float64("max-error-rate", -1, "Fail the run if more than this percentage of all pings are errors, e.g. 1.5")
+	maxP95Flag           = flag.Float64("max-p95", -1, "Fail the run if the p95 latency of any operation is above this many milliseconds")
+	maxTotalP95Flag      = flag.Float64("max-total-p95", -1, "Fail the run if the p95 latency of all operations together is above this many milliseconds")
+	failUndocumentedFlag = flag.Bool("fail-undocumented", false, "Fail the run on any status code the spec does not declare (validates responses)")
+	failViolationsFlag   = flag.Bool("fail-violations", false, "Fail the run on any response violating its content type or schema (validates responses)")
+	sloFlag              = flag.String("slo", "", "A JSON/YAML file with latency and error budgets by operationId, tag or path pattern")
+	scenarioFlag         = flag.String("scenario", "", "A JSON/YAML file of operations to ping in order, extracting variables from responses for later requests")
 
 	basePath string
+	seed     int64
 )
 
 // RegExp pattern for path filter
@@ -43,15 +69,17 @@ var progressWriter = progress.NewWriter()
 
 // Init some short variable options
 func init() {
-	flag.StringVar(inputFlag, "i", "", "*The path/url to the Swagger/OpenAPI 3.0 input source")
+	flag.StringVar(inputFlag, "i", "", "*The path/url to the Swagger/OpenAPI 3.0 input source (JSON or YAML), \"-\" for stdin")
 	flag.StringVar(basePathFlag, "b", "", "The base url to query")
-	flag.StringVar(outputFlag, "o", "console", "The output format. Options: console, csv, html, md, json")
+	flag.StringVar(outputFlag, "o", "console", "A comma separated list of output formats, each with an optional file, e.g. 'console,json=reports/{{TIMESTAMP}}.json'. Options: console, csv, html, md, json, junit")
 	flag.IntVar(workerFlag, "w", 1, "The amount of parallel workers to use")
 	flag.IntVar(timeoutFlag, "t", 5, "The timeout in seconds per request")
 	flag.IntVar(loopFlag, "l", 1, "How often to loop through all calls")
 	flag.BoolVar(responseFlag, "r", false, "Include the response body in the output")
 	flag.StringVar(methodsFlag, "m", "[\"GET\",\"POST\"]", "An array of query methods to include, e.g. '[\"GET\", \"POST\"]'")
 	flag.StringVar(filterFlag, "f", "", "A regular expression to filter matching paths. Only will be pinged!")
+	flag.Int64Var(seedFlag, "s", 0, "The seed for random parameters and bodies to replay a run, 0 for a random seed")
+	flag.StringVar(paramsFlag, "p", "", "A JSON/YAML file with fixture values by parameter name, operationId or path pattern")
 
 	// Pre-set the progress writer
 	progressWriter.SetAutoStop(true)
@@ -85,6 +113,13 @@ func parseBase(swagger *openapi3.Swagger) {
 
 		//
 		if servers != nil && len(servers) > 0 {
+			// Stdin is taken by the input document, nothing to pick from
+			if *inputFlag == StdinInput {
+				log.Println(fmt.Sprintf("No base given. Using the first server %s", servers[0]))
+				basePath = servers[0]
+				return
+			}
+
 			fmt.Println("No base given. Select a server.")
 			for k, v := range servers {
 				fmt.Println(fmt.Sprintf("[%d] %s", k, v))
@@ -124,6 +159,15 @@ func parseHeader() {
 	}
 }
 
+// Seed all random values with the given or a random seed, which is recorded to replay the run
+func parseSeed() {
+	seed = *seedFlag
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	seededRand = newRand(seed)
+}
+
 // Check for a path filter regular expression
 func parseFilter() {
 	if filterFlag != nil && *filterFlag != "" {
@@ -133,60 +177,106 @@ func parseFilter() {
 	}
 }
 
+// Check if the path is excluded by the filter, if set
+func isFilteredPath(path string) bool {
+	return regExPathFilterPattern != nil && !regExPathFilterPattern.MatchString(path)
+}
+
 // Parse all query methods to includefor calls
 func parseQueryMethods() {
 	err := json.Unmarshal([]byte(*methodsFlag), &QueryMethods)
 	checkFatalError(err)
 }
 
-// Create a "pingable" url with parameters
-func parseUrl(path string, operation *openapi3.Operation) (string, bool) {
-	// Filter paths, if set
-	if regExPathFilterPattern != nil && !regExPathFilterPattern.Match([]byte(path)) {
-		return "", false
+// Generate a request body from the operations request body, preferring examples of the spec.
+// Fails if a required body cannot be generated for any supported media type
+func parseBody(random *rand.Rand, operation *openapi3.Operation) (string, []byte, bool) {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return "", nil, true
 	}
+	requestBody := operation.RequestBody.Value
 
-	//
-	parsed := true
-	for _, v := range operation.Parameters {
-		// Required or path parameter, which is always required
-		if v.Value.Required || strings.ToLower(v.Value.In) == "path" {
-			if v.Value.Schema != nil {
-				var randomParameter string
-
-				// Check supported parameter types
-				switch v.Value.Schema.Value.Type {
-				case "integer":
-					min := 0
-					max := 100
-					if v.Value.Schema.Value.Min != nil {
-						min = int(*v.Value.Schema.Value.Min)
-					}
-					if v.Value.Schema.Value.Max != nil {
-						max = int(*v.Value.Schema.Value.Max)
-					}
-					randomParameter = strconv.Itoa(seededRand.Intn(max-min+1) + min)
-				case "string":
-					length := 1
-					if v.Value.Schema.Value.MinLength > 1 {
-						length = int(v.Value.Schema.Value.MinLength)
-					}
-					if v.Value.Schema.Value.MaxLength != nil {
-						length = int(*v.Value.Schema.Value.MaxLength)
-					}
-					randomParameter = getRandString(length)
-				default:
-					// Cannot parse at least one parameter => don't ping!
-					parsed = false
-				}
-				//
-				if randomParameter != "" {
-					path = strings.Replace(path, "{"+v.Value.Name+"}", randomParameter, 1)
-				}
-			} else {
-				parsed = false
+	// Check supported media types by preference
+	for _, contentType := range getSortedContentTypes(requestBody.Content) {
+		mediaType := requestBody.Content[contentType]
+		value, ok := getMediaTypeExample(mediaType)
+		if !ok {
+			value, ok = generateValue(random, mediaType.Schema, 0)
+		}
+		if !ok {
+			continue
+		}
+
+		switch {
+		case isJSONContentType(contentType):
+			data, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			if contentType == "*/*" {
+				contentType = "application/json"
+			}
+			return contentType, data, true
+		case contentType == "application/x-www-form-urlencoded":
+			object, isObject := value.(map[string]interface{})
+			if !isObject {
+				continue
 			}
+			form := url.Values{}
+			for key, v := range object {
+				form.Set(key, fmt.Sprint(v))
+			}
+			return contentType, []byte(form.Encode()), true
+		case strings.HasPrefix(contentType, "text/"):
+			return contentType, []byte(fmt.Sprint(value)), true
 		}
 	}
-	return basePath + path, parsed
+
+	// Optional bodies can be left out
+	return "", nil, !requestBody.Required
+}
+
+// Sort the content types by preference: JSON, forms, text and anything else
+func getSortedContentTypes(content openapi3.Content) []string {
+	contentTypes := make([]string, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	rank := func(contentType string) int {
+		switch {
+		case isJSONContentType(contentType) && contentType != "*/*":
+			return 0
+		case contentType == "application/x-www-form-urlencoded":
+			return 1
+		case strings.HasPrefix(contentType, "text/"):
+			return 2
+		case contentType == "*/*":
+			return 3
+		}
+		return 4
+	}
+	sort.Slice(contentTypes, func(i, j int) bool {
+		if rank(contentTypes[i]) != rank(contentTypes[j]) {
+			return rank(contentTypes[i]) < rank(contentTypes[j])
+		}
+		return contentTypes[i] < contentTypes[j]
+	})
+	return contentTypes
+}
+
+// Get the example or the first of all examples (by name) of a media type
+func getMediaTypeExample(mediaType *openapi3.MediaType) (interface{}, bool) {
+	if mediaType == nil {
+		return nil, false
+	}
+	if mediaType.Example != nil {
+		return mediaType.Example, true
+	}
+	return getFirstExample(mediaType.Examples)
+}
+
+// JSON, any +json or wildcard content type
+func isJSONContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json") || contentType == "*/*"
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// A config of values per operation by operationId, tag and path pattern, e.g. the SLOs or weights
+type OperationConfig struct {
+	Operations map[string]json.RawMessage `json:"operations,omitempty"`
+	Tags       map[string]json.RawMessage `json:"tags,omitempty"`
+	Paths      map[string]json.RawMessage `json:"paths,omitempty"`
+
+	pathPatterns []*PathPattern
+}
+
+// A compiled path pattern of a config
+type PathPattern struct {
+	pattern string
+	regExp  *regexp.Regexp
+}
+
+// Load the per-operation config of a JSON/YAML file, an empty one without input
+func loadOperationConfig(input string) (*OperationConfig, error) {
+	config := &OperationConfig{}
+	if input != "" {
+		if err := loadConfig(input, config); err != nil {
+			return nil, err
+		}
+	}
+	patterns := make([]string, 0, len(config.Paths))
+	for pattern := range config.Paths {
+		patterns = append(patterns, pattern)
+	}
+	var err error
+	config.pathPatterns, err = compilePathPatterns(patterns)
+	return config, err
+}
+
+// Compile the path patterns, sorted for a stable precedence
+func compilePathPatterns(patterns []string) ([]*PathPattern, error) {
+	sort.Strings(patterns)
+	pathPatterns := make([]*PathPattern, len(patterns))
+	for i, pattern := range patterns {
+		regExp, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		pathPatterns[i] = &PathPattern{pattern: pattern, regExp: regExp}
+	}
+	return pathPatterns, nil
+}
+
+// Unmarshal the value of an operation by operationId, path pattern, tag (in this order) or its extension into v.
+// Returns false if none is given
+func (config *OperationConfig) unmarshal(operation Operation, extension string, v interface{}) (bool, error) {
+	source := "config"
+	data, ok := config.match(operation)
+	if !ok {
+		var value interface{}
+		if value, ok = operation.Operation.Extensions[extension]; !ok {
+			return false, nil
+		}
+		source = extension
+		if data, ok = value.(json.RawMessage); !ok {
+			var err error
+			if data, err = json.Marshal(value); err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("the %s of '%s' is invalid: %s", source, getOperationKey(operation.Method, operation.Path), err)
+	}
+	return true, nil
+}
+
+// Get the config value of an operation by operationId, path pattern and tag (in this order), null values are skipped
+func (config *OperationConfig) match(operation Operation) (json.RawMessage, bool) {
+	if operation.Operation.OperationID != "" {
+		if data, ok := config.Operations[operation.Operation.OperationID]; ok && !isNull(data) {
+			return data, true
+		}
+	}
+	for _, pathPattern := range config.pathPatterns {
+		if data := config.Paths[pathPattern.pattern]; !isNull(data) && pathPattern.regExp.MatchString(operation.Path) {
+			return data, true
+		}
+	}
+	for _, tag := range operation.Operation.Tags {
+		if data, ok := config.Tags[tag]; ok && !isNull(data) {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// Get the config entries matching none of the operations, e.g. "operations getUser", null values are skipped
+func (config *OperationConfig) unmatched(operations []Operation) []string {
+	entries := make([]string, 0)
+	for _, operationId := range getSortedConfigKeys(config.Operations) {
+		matched := false
+		for _, operation := range operations {
+			matched = matched || operation.Operation.OperationID == operationId
+		}
+		if !matched {
+			entries = append(entries, "operations "+operationId)
+		}
+	}
+	for _, pathPattern := range config.pathPatterns {
+		matched := isNull(config.Paths[pathPattern.pattern])
+		for _, operation := range operations {
+			matched = matched || pathPattern.regExp.MatchString(operation.Path)
+		}
+		if !matched {
+			entries = append(entries, "paths "+pathPattern.pattern)
+		}
+	}
+	for _, tag := range getSortedConfigKeys(config.Tags) {
+		matched := false
+		for _, operation := range operations {
+			_, tagged := contains(operation.Operation.Tags, tag)
+			matched = matched || tagged
+		}
+		if !matched {
+			entries = append(entries, "tags "+tag)
+		}
+	}
+	return entries
+}
+
+// Get the keys of the non-null config values, sorted
+func getSortedConfigKeys(values map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(values))
+	for key, data := range values {
+		if !isNull(data) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Check if the raw JSON is missing or null
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
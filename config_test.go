@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi3"
+	"reflect"
+	"testing"
+)
+
+func TestOperationConfigUnmarshal(t *testing.T) {
+	config := &OperationConfig{
+		Operations: map[string]json.RawMessage{"getPet": json.RawMessage(`1`), "nulled": json.RawMessage(`null`)},
+		Paths:      map[string]json.RawMessage{"^/pets": json.RawMessage(`2`), "^/pet": json.RawMessage(`3`)},
+		Tags:       map[string]json.RawMessage{"store": json.RawMessage(`4`), "admin": json.RawMessage(`5`)},
+	}
+	patterns := make([]string, 0, len(config.Paths))
+	for pattern := range config.Paths {
+		patterns = append(patterns, pattern)
+	}
+	var err error
+	if config.pathPatterns, err = compilePathPatterns(patterns); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		operationId string
+		tags        []string
+		extensions  map[string]interface{}
+		expected    float64
+		found       bool
+	}{
+		{"operationId first", "/pets/{id}", "getPet", []string{"store"}, nil, 1, true},
+		{"sorted path pattern", "/pets/{id}", "", []string{"store"}, nil, 3, true},
+		{"tag", "/orders", "", []string{"other", "admin", "store"}, nil, 5, true},
+		{"null skipped", "/orders", "nulled", []string{"store"}, nil, 4, true},
+		{"extension", "/orders", "", nil, map[string]interface{}{"x-test": json.RawMessage(`6`)}, 6, true},
+		{"config before extension", "/orders", "getPet", nil, map[string]interface{}{"x-test": json.RawMessage(`6`)}, 1, true},
+		{"none", "/orders", "listOrders", []string{"other"}, nil, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			operation := Operation{Path: test.path, Method: "GET", Operation: &openapi3.Operation{OperationID: test.operationId, Tags: test.tags}}
+			operation.Operation.Extensions = test.extensions
+			actual := 0.0
+			found, err := config.unmarshal(operation, "x-test", &actual)
+			if err != nil || found != test.found || actual != test.expected {
+				t.Errorf("%g (%t, %v), expected %g (%t)", actual, found, err, test.expected, test.found)
+			}
+		})
+	}
+}
+
+func TestOperationConfigUnmatched(t *testing.T) {
+	config, err := loadOperationConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Operations = map[string]json.RawMessage{"getPet": json.RawMessage(`1`), "deletePet": json.RawMessage(`2`), "nulled": json.RawMessage(`null`)}
+	config.Paths = map[string]json.RawMessage{"^/pets": json.RawMessage(`3`), "^/orders": json.RawMessage(`4`)}
+	config.Tags = map[string]json.RawMessage{"store": json.RawMessage(`5`), "admin": json.RawMessage(`6`)}
+	if config.pathPatterns, err = compilePathPatterns([]string{"^/pets", "^/orders"}); err != nil {
+		t.Fatal(err)
+	}
+
+	operations := []Operation{
+		{Path: "/pets/{id}", Method: "GET", Operation: &openapi3.Operation{OperationID: "getPet", Tags: []string{"store"}}},
+		{Path: "/users", Method: "GET", Operation: &openapi3.Operation{}},
+	}
+	expected := []string{"operations deletePet", "paths ^/orders", "tags admin"}
+	if actual := config.unmatched(operations); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v, expected %v", actual, expected)
+	}
+}
+
+func TestCompilePathPatterns(t *testing.T) {
+	pathPatterns, err := compilePathPatterns([]string{"^/b", "^/a", "^/c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"^/a", "^/b", "^/c"} {
+		if pathPatterns[i].pattern != expected {
+			t.Errorf("pattern %d is '%s', expected '%s'", i, pathPatterns[i].pattern, expected)
+		}
+	}
+	if _, err = compilePathPatterns([]string{"^/("}); err == nil {
+		t.Errorf("compiled an invalid pattern")
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"strings"
+)
+
+// Swagger 2.0 definition prefix and its OpenAPI 3.0 counterpart
+const (
+	swagger2DefinitionsPrefix = "#/definitions/"
+	openApi3SchemasPrefix     = "#/components/schemas/"
+)
+
+// Convert a Swagger 2.0 document into an OpenAPI 3.0 one, i.e.
+// host/basePath/schemes into servers, body and form params into request bodies and definitions into components
+func convertSwagger2(data []byte) (*openapi3.Swagger, error) {
+	swagger2 := &openapi2.Swagger{}
+	if err := json.Unmarshal(data, swagger2); err != nil {
+		return nil, err
+	}
+	mediaTypes := Swagger2MediaTypes{}
+	if err := json.Unmarshal(data, &mediaTypes); err != nil {
+		return nil, err
+	}
+
+	// The converter cannot handle missing schemes ("https://" is taken as scheme) or hosts (no server at all)
+	if len(swagger2.Schemes) == 0 {
+		swagger2.Schemes = []string{"https"}
+	}
+	// Nested definition references are not rewritten by the converter
+	convertSwagger2Refs(swagger2)
+	// Flows the converter does not support are converted manually afterwards
+	clientCredentials := extractSwagger2ApplicationFlows(swagger2)
+	// Form parameters are only converted if they come first, convert all of them manually afterwards
+	formParameters := extractSwagger2FormParameters(swagger2)
+
+	swagger, err := openapi2conv.ToV3Swagger(swagger2)
+	if err != nil {
+		return nil, err
+	}
+
+	// A relative server if only a basePath is given, a basePath of "/" is none
+	if swagger2.Host == "" && strings.Trim(swagger2.BasePath, "/") != "" {
+		swagger.AddServer(&openapi3.Server{URL: swagger2.BasePath})
+	}
+	// The paths start with a slash, a trailing one of the server would double it
+	for _, server := range swagger.Servers {
+		server.URL = strings.TrimRight(server.URL, "/")
+	}
+	// Re-add all client credentials (application) flows
+	for name, securityScheme := range clientCredentials {
+		if swagger.Components.SecuritySchemes == nil {
+			swagger.Components.SecuritySchemes = make(map[string]*openapi3.SecuritySchemeRef)
+		}
+		swagger.Components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{
+			Value: &openapi3.SecurityScheme{
+				Type:        "oauth2",
+				Description: securityScheme.Description,
+				Flows: &openapi3.OAuthFlows{
+					ClientCredentials: &openapi3.OAuthFlow{
+						TokenURL: securityScheme.TokenURL,
+						Scopes:   securityScheme.Scopes,
+					},
+				},
+			},
+		}
+	}
+	// Body parameters are always assumed to be JSON, respect any given consumes instead
+	convertSwagger2Consumes(swagger2, swagger, mediaTypes.Consumes)
+	if err = convertSwagger2FormParameters(swagger2, swagger, formParameters, mediaTypes.Consumes); err != nil {
+		return nil, err
+	}
+
+	return swagger, nil
+}
+
+// Rewrite all definition references of all schemas to component references
+func convertSwagger2Refs(swagger2 *openapi2.Swagger) {
+	for _, schema := range swagger2.Definitions {
+		convertSwagger2SchemaRef(schema)
+	}
+	for _, parameter := range swagger2.Parameters {
+		convertSwagger2Parameter(parameter)
+	}
+	for _, response := range swagger2.Responses {
+		convertSwagger2SchemaRef(response.Schema)
+	}
+	for _, pathItem := range swagger2.Paths {
+		for _, parameter := range pathItem.Parameters {
+			convertSwagger2Parameter(parameter)
+		}
+		for _, operation := range pathItem.Operations() {
+			for _, parameter := range operation.Parameters {
+				convertSwagger2Parameter(parameter)
+			}
+			for _, response := range operation.Responses {
+				convertSwagger2SchemaRef(response.Schema)
+			}
+		}
+	}
+}
+
+// Rewrite the references of a parameters schema and items
+func convertSwagger2Parameter(parameter *openapi2.Parameter) {
+	if parameter == nil {
+		return
+	}
+	convertSwagger2SchemaRef(parameter.Schema)
+	convertSwagger2SchemaRef(parameter.Items)
+}
+
+// Rewrite the references of a schema and all its nested schemas
+func convertSwagger2SchemaRef(schema *openapi3.SchemaRef) {
+	if schema == nil {
+		return
+	}
+	if strings.HasPrefix(schema.Ref, swagger2DefinitionsPrefix) {
+		schema.Ref = openApi3SchemasPrefix + strings.TrimPrefix(schema.Ref, swagger2DefinitionsPrefix)
+	}
+	if schema.Value == nil {
+		return
+	}
+	for _, v := range schema.Value.AllOf {
+		convertSwagger2SchemaRef(v)
+	}
+	for _, v := range schema.Value.OneOf {
+		convertSwagger2SchemaRef(v)
+	}
+	for _, v := range schema.Value.AnyOf {
+		convertSwagger2SchemaRef(v)
+	}
+	for _, v := range schema.Value.Properties {
+		convertSwagger2SchemaRef(v)
+	}
+	convertSwagger2SchemaRef(schema.Value.Not)
+	convertSwagger2SchemaRef(schema.Value.Items)
+	convertSwagger2SchemaRef(schema.Value.AdditionalProperties)
+}
+
+// Remove all OAuth2 flows the converter does not support and return the client credential (application) ones
+func extractSwagger2ApplicationFlows(swagger2 *openapi2.Swagger) map[string]*openapi2.SecurityScheme {
+	result := make(map[string]*openapi2.SecurityScheme)
+	for name, securityScheme := range swagger2.SecurityDefinitions {
+		if securityScheme == nil || securityScheme.Type != "oauth2" {
+			continue
+		}
+		// The spec names it "accessCode", the converter expects it lowercase
+		securityScheme.Flow = strings.ToLower(securityScheme.Flow)
+		if securityScheme.Flow == "application" {
+			result[name] = securityScheme
+			delete(swagger2.SecurityDefinitions, name)
+		}
+	}
+	return result
+}
+
+// Move JSON request bodies to the declared consumes media types (operation before global)
+func convertSwagger2Consumes(swagger2 *openapi2.Swagger, swagger *openapi3.Swagger, consumes []string) {
+	for path, pathItem := range swagger2.Paths {
+		for method, operation2 := range pathItem.Operations() {
+			mediaTypes := consumes
+			if len(operation2.Consumes) > 0 {
+				mediaTypes = operation2.Consumes
+			}
+			if len(mediaTypes) == 0 {
+				continue
+			}
+
+			openApiPathItem := swagger.Paths[path]
+			if openApiPathItem == nil {
+				continue
+			}
+			operation := openApiPathItem.GetOperation(method)
+			if operation == nil || operation.RequestBody == nil || operation.RequestBody.Value == nil {
+				continue
+			}
+			content := operation.RequestBody.Value.Content
+			jsonMediaType := content.Get("application/json")
+			if jsonMediaType == nil || len(content) != 1 {
+				continue
+			}
+			operation.RequestBody.Value.Content = openapi3.NewContent()
+			for _, mediaType := range mediaTypes {
+				operation.RequestBody.Value.Content[mediaType] = jsonMediaType
+			}
+		}
+	}
+}
+
+// Remove all form parameters of the operations (and their paths) and return them by operation key
+func extractSwagger2FormParameters(swagger2 *openapi2.Swagger) map[string][]*openapi2.Parameter {
+	result := make(map[string][]*openapi2.Parameter)
+	for path, pathItem := range swagger2.Paths {
+		pathParameters, _ := splitSwagger2FormParameters(pathItem.Parameters)
+		for method, operation := range pathItem.Operations() {
+			formParameters, parameters := splitSwagger2FormParameters(operation.Parameters)
+			// The operation parameters override the ones of the path by name
+			for _, pathParameter := range pathParameters {
+				if !hasSwagger2Parameter(formParameters, pathParameter.Name) {
+					formParameters = append(formParameters, pathParameter)
+				}
+			}
+			if len(formParameters) > 0 {
+				result[getOperationKey(method, path)] = formParameters
+				operation.Parameters = parameters
+			}
+		}
+		_, pathItem.Parameters = splitSwagger2FormParameters(pathItem.Parameters)
+	}
+	return result
+}
+
+// Split the form parameters from all others
+func splitSwagger2FormParameters(parameters openapi2.Parameters) ([]*openapi2.Parameter, openapi2.Parameters) {
+	formParameters := make([]*openapi2.Parameter, 0)
+	others := make(openapi2.Parameters, 0, len(parameters))
+	for _, parameter := range parameters {
+		if parameter != nil && parameter.In == "formData" {
+			formParameters = append(formParameters, parameter)
+		} else {
+			others = append(others, parameter)
+		}
+	}
+	return formParameters, others
+}
+
+// Check if a parameter of the name is in the list
+func hasSwagger2Parameter(parameters []*openapi2.Parameter, name string) bool {
+	for _, parameter := range parameters {
+		if parameter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert the form parameters into an object request body of the declared form consumes (operation before global),
+// URL encoded by default and multipart for files
+func convertSwagger2FormParameters(swagger2 *openapi2.Swagger, swagger *openapi3.Swagger, formParameters map[string][]*openapi2.Parameter, consumes []string) error {
+	for path, pathItem := range swagger2.Paths {
+		for method, operation2 := range pathItem.Operations() {
+			parameters, ok := formParameters[getOperationKey(method, path)]
+			if !ok || swagger.Paths[path] == nil || swagger.Paths[path].GetOperation(method) == nil {
+				continue
+			}
+
+			schema := openapi3.NewObjectSchema()
+			required := false
+			hasFile := false
+			for _, parameter := range parameters {
+				property, _, err := openapi2conv.ToV3Parameter(parameter)
+				if err != nil {
+					return err
+				}
+				propertySchema := openapi3.NewSchemaRef("", openapi3.NewStringSchema())
+				if property != nil && property.Value != nil && property.Value.Schema != nil {
+					propertySchema = property.Value.Schema
+				}
+				// Files are binary strings in OpenAPI 3.0
+				if parameter.Type == "file" {
+					propertySchema = openapi3.NewSchemaRef("", openapi3.NewStringSchema().WithFormat("binary"))
+					hasFile = true
+				}
+				schema.WithPropertyRef(parameter.Name, propertySchema)
+				if parameter.Required {
+					schema.Required = append(schema.Required, parameter.Name)
+					required = true
+				}
+			}
+
+			mediaTypes := consumes
+			if len(operation2.Consumes) > 0 {
+				mediaTypes = operation2.Consumes
+			}
+			content := openapi3.NewContent()
+			for _, mediaType := range mediaTypes {
+				if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+					content[mediaType] = openapi3.NewMediaType().WithSchema(schema)
+				}
+			}
+			if len(content) == 0 {
+				mediaType := "application/x-www-form-urlencoded"
+				if hasFile {
+					mediaType = "multipart/form-data"
+				}
+				content[mediaType] = openapi3.NewMediaType().WithSchema(schema)
+			}
+			swagger.Paths[path].GetOperation(method).RequestBody = &openapi3.RequestBodyRef{
+				Value: openapi3.NewRequestBody().WithContent(content).WithRequired(required),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// A Swagger 2.0 document with a body parameter, nested definitions and an application flow
+const testSwagger2 = `{
+	"swagger": "2.0",
+	"info": {"title": "Pets", "version": "1.0"},
+	"paths": {
+		"/pets": {
+			"post": {
+				"parameters": [{"name": "pet", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Pet"}}],
+				"responses": {"201": {"description": "Created", "schema": {"$ref": "#/definitions/Pet"}}}
+			}
+		},
+		"/owners": {
+			"post": {
+				"consumes": ["application/xml"],
+				"parameters": [{"name": "owner", "in": "body", "schema": {"$ref": "#/definitions/Owner"}}],
+				"responses": {"201": {"description": "Created"}}
+			}
+		}
+	},
+	"definitions": {
+		"Pet": {
+			"type": "object",
+			"properties": {
+				"owner": {"$ref": "#/definitions/Owner"},
+				"tags": {"type": "array", "items": {"$ref": "#/definitions/Tag"}}
+			}
+		},
+		"Owner": {"type": "object", "properties": {"name": {"type": "string"}}},
+		"Tag": {"type": "string"}
+	},
+	"securityDefinitions": {
+		"app": {"type": "oauth2", "flow": "application", "tokenUrl": "https://auth.example.com/token", "scopes": {"read": "Read"}},
+		"code": {"type": "oauth2", "flow": "accessCode", "authorizationUrl": "https://auth.example.com/authorize", "tokenUrl": "https://auth.example.com/token", "scopes": {}}
+	}
+}`
+
+func TestConvertSwagger2Servers(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected []string
+	}{
+		{"host and base path", `{"swagger": "2.0", "host": "api.example.com", "basePath": "/v1", "schemes": ["http", "https"], "paths": {}}`, []string{"http://api.example.com/v1", "https://api.example.com/v1"}},
+		{"default scheme", `{"swagger": "2.0", "host": "api.example.com", "basePath": "/v1", "paths": {}}`, []string{"https://api.example.com/v1"}},
+		{"host only", `{"swagger": "2.0", "host": "api.example.com:8080", "schemes": ["http"], "paths": {}}`, []string{"http://api.example.com:8080"}},
+		{"base path only", `{"swagger": "2.0", "basePath": "/v1", "paths": {}}`, []string{"/v1"}},
+		{"root base path", `{"swagger": "2.0", "host": "api.example.com", "basePath": "/", "schemes": ["http"], "paths": {}}`, []string{"http://api.example.com"}},
+		{"trailing slash", `{"swagger": "2.0", "host": "api.example.com", "basePath": "/v1/", "schemes": ["http"], "paths": {}}`, []string{"http://api.example.com/v1"}},
+		{"root base path only", `{"swagger": "2.0", "basePath": "/", "paths": {}}`, []string{}},
+		{"none", `{"swagger": "2.0", "paths": {}}`, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			swagger, err := convertSwagger2([]byte(test.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := make([]string, 0, len(swagger.Servers))
+			for _, server := range swagger.Servers {
+				actual = append(actual, server.URL)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("%v, expected %v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestConvertSwagger2RequestBodies(t *testing.T) {
+	swagger, err := convertSwagger2([]byte(testSwagger2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		path       string
+		mediaTypes []string
+		ref        string
+	}{
+		{"json by default", "/pets", []string{"application/json"}, "#/components/schemas/Pet"},
+		{"consumes", "/owners", []string{"application/xml"}, "#/components/schemas/Owner"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			operation := swagger.Paths[test.path].Post
+			if operation == nil || operation.RequestBody == nil || operation.RequestBody.Value == nil {
+				t.Fatalf("'%s' has no request body", test.path)
+			}
+			content := operation.RequestBody.Value.Content
+			if len(content) != len(test.mediaTypes) {
+				t.Errorf("%d media types, expected %v", len(content), test.mediaTypes)
+			}
+			for _, mediaType := range test.mediaTypes {
+				if content.Get(mediaType) == nil || content.Get(mediaType).Schema.Ref != test.ref {
+					t.Errorf("'%s' is missing or not '%s'", mediaType, test.ref)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertSwagger2Refs(t *testing.T) {
+	swagger, err := convertSwagger2([]byte(testSwagger2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pet := swagger.Components.Schemas["Pet"]
+	if pet == nil || pet.Value == nil {
+		t.Fatal("the Pet definition is missing")
+	}
+	tests := []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{"property", pet.Value.Properties["owner"].Ref, "#/components/schemas/Owner"},
+		{"items", pet.Value.Properties["tags"].Value.Items.Ref, "#/components/schemas/Tag"},
+		{"response", swagger.Paths["/pets"].Post.Responses.Get(201).Value.Content.Get("application/json").Schema.Ref, "#/components/schemas/Pet"},
+	}
+	for _, test := range tests {
+		if test.actual != test.expected {
+			t.Errorf("the %s reference is '%s', expected '%s'", test.name, test.actual, test.expected)
+		}
+	}
+}
+
+func TestConvertSwagger2ApplicationFlows(t *testing.T) {
+	swagger, err := convertSwagger2([]byte(testSwagger2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := swagger.Components.SecuritySchemes["app"]
+	if app == nil || app.Value.Flows == nil || app.Value.Flows.ClientCredentials == nil {
+		t.Fatal("the application flow is missing")
+	}
+	if flow := app.Value.Flows.ClientCredentials; flow.TokenURL != "https://auth.example.com/token" || len(flow.Scopes) != 1 {
+		t.Errorf("the client credentials flow is %+v", flow)
+	}
+	code := swagger.Components.SecuritySchemes["code"]
+	if code == nil || code.Value.Flows == nil || code.Value.Flows.AuthorizationCode == nil {
+		t.Errorf("the access code flow is missing")
+	}
+}
+
+func TestConvertSwagger2FormParameters(t *testing.T) {
+	document := `{
+		"swagger": "2.0",
+		"paths": {
+			"/pets/{id}": {
+				"parameters": [{"name": "note", "in": "formData", "type": "string"}],
+				"post": {
+					"parameters": [
+						{"name": "id", "in": "path", "required": true, "type": "integer"},
+						{"name": "name", "in": "formData", "required": true, "type": "string", "maxLength": 10},
+						{"name": "age", "in": "formData", "type": "integer", "minimum": 0}
+					],
+					"responses": {"200": {"description": "OK"}}
+				}
+			},
+			"/pets/{id}/photo": {
+				"post": {
+					"parameters": [
+						{"name": "id", "in": "path", "required": true, "type": "integer"},
+						{"name": "photo", "in": "formData", "type": "file"}
+					],
+					"responses": {"200": {"description": "OK"}}
+				}
+			},
+			"/owners": {
+				"post": {
+					"consumes": ["multipart/form-data", "application/x-www-form-urlencoded"],
+					"parameters": [{"name": "name", "in": "formData", "type": "string"}],
+					"responses": {"200": {"description": "OK"}}
+				}
+			}
+		}
+	}`
+	swagger, err := convertSwagger2([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		path       string
+		mediaTypes []string
+		properties []string
+		required   []string
+		parameters int
+	}{
+		{"url encoded", "/pets/{id}", []string{"application/x-www-form-urlencoded"}, []string{"age", "name", "note"}, []string{"name"}, 1},
+		{"file", "/pets/{id}/photo", []string{"multipart/form-data"}, []string{"photo"}, nil, 1},
+		{"consumes", "/owners", []string{"application/x-www-form-urlencoded", "multipart/form-data"}, []string{"name"}, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			operation := swagger.Paths[test.path].Post
+			if operation == nil || operation.RequestBody == nil || operation.RequestBody.Value == nil {
+				t.Fatalf("'%s' has no request body", test.path)
+			}
+			if len(operation.Parameters) != test.parameters || len(swagger.Paths[test.path].Parameters) != 0 {
+				t.Errorf("%d parameters left, expected %d", len(operation.Parameters), test.parameters)
+			}
+			requestBody := operation.RequestBody.Value
+			if requestBody.Required != (len(test.required) > 0) {
+				t.Errorf("the request body is required: %t", requestBody.Required)
+			}
+			if len(requestBody.Content) != len(test.mediaTypes) {
+				t.Errorf("%d media types, expected %v", len(requestBody.Content), test.mediaTypes)
+			}
+			for _, mediaType := range test.mediaTypes {
+				content := requestBody.Content.Get(mediaType)
+				if content == nil {
+					t.Fatalf("'%s' is missing", mediaType)
+				}
+				properties := make([]string, 0)
+				for name := range content.Schema.Value.Properties {
+					properties = append(properties, name)
+				}
+				sort.Strings(properties)
+				if !reflect.DeepEqual(properties, test.properties) || !reflect.DeepEqual(content.Schema.Value.Required, test.required) {
+					t.Errorf("properties %v (required %v), expected %v (required %v)", properties, content.Schema.Value.Required, test.properties, test.required)
+				}
+			}
+		})
+	}
+
+	// Files are binary strings and the form values keep their constraints
+	if photo := swagger.Paths["/pets/{id}/photo"].Post.RequestBody.Value.Content.Get("multipart/form-data").Schema.Value.Properties["photo"].Value; photo.Type != "string" || photo.Format != "binary" {
+		t.Errorf("the file is of type '%s' and format '%s'", photo.Type, photo.Format)
+	}
+	if name := swagger.Paths["/pets/{id}"].Post.RequestBody.Value.Content.Get("application/x-www-form-urlencoded").Schema.Value.Properties["name"].Value; name.MaxLength == nil || *name.MaxLength != 10 {
+		t.Errorf("the max length of the name is lost")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// The exit code of a run failing its criteria, fatal errors exit with 1
+const ExitCodeCriteria = 2
+
+// Enable the response validation if a criterion depends on it
+func parseCriteria() {
+	if *failUndocumentedFlag || *failViolationsFlag {
+		*validateFlag = true
+	}
+}
+
+// Check if any pass/fail criterion is given
+func hasCriteria() bool {
+	return *maxErrorRateFlag >= 0 || *maxP95Flag >= 0 || *maxTotalP95Flag >= 0 || *failUndocumentedFlag || *failViolationsFlag || len(slos) > 0
+}
+
+// Evaluate all given criteria on the flushed results, returning a message per failure
+func evaluateCriteria() []string {
+	failures := make([]string, 0)
+	total := newHistogram()
+	pings, errors := 0, 0
+	for _, key := range getSortedResultKeys() {
+		result := Results[key]
+		total.merge(result.Histogram)
+		pings += result.Successes + result.Errors
+		errors += result.Errors
+
+		if *maxP95Flag >= 0 && result.Latency.Count > 0 && result.Latency.P95 > *maxP95Flag {
+			failures = append(failures, fmt.Sprintf("p95 of '%s' is %s ms, above %s ms", key, formatMS(result.Latency.P95), formatMS(*maxP95Flag)))
+		}
+		if *failUndocumentedFlag && result.Violations[ViolationStatus] > 0 {
+			failures = append(failures, fmt.Sprintf("'%s' returned %d undocumented status codes", key, result.Violations[ViolationStatus]))
+		}
+		if len(result.SLOBreaches) > 0 {
+			failures = append(failures, fmt.Sprintf("'%s' breached its SLO: %s", key, strings.Join(result.SLOBreaches, ", ")))
+		}
+		if *failViolationsFlag {
+			if violations := result.Violations[ViolationContentType] + result.Violations[ViolationSchema]; violations > 0 {
+				failures = append(failures, fmt.Sprintf("'%s' returned %d responses violating the content type or schema", key, violations))
+			}
+		}
+	}
+
+	if *maxErrorRateFlag >= 0 && pings > 0 {
+		if errorRate := float64(errors) / float64(pings) * 100; errorRate > *maxErrorRateFlag {
+			failures = append(failures, fmt.Sprintf("error rate is %.2f%% (%d of %d pings), above %.2f%%", errorRate, errors, pings, *maxErrorRateFlag))
+		}
+	}
+	if *maxTotalP95Flag >= 0 {
+		if latency := total.getLatency(); latency.Count > 0 && latency.P95 > *maxTotalP95Flag {
+			failures = append(failures, fmt.Sprintf("p95 of all operations is %s ms, above %s ms", formatMS(latency.P95), formatMS(*maxTotalP95Flag)))
+		}
+	}
+	return failures
+}
+
+// Log a summary of the criteria and exit with a non-zero code if any failed
+func checkCriteria() {
+	if !hasCriteria() {
+		return
+	}
+	failures := evaluateCriteria()
+	if len(failures) == 0 {
+		log.Println("[aPing] All criteria passed")
+		return
+	}
+	log.Println(fmt.Sprintf("[aPing] %d criteria failed:\n - %s", len(failures), strings.Join(failures, "\n - ")))
+	os.Exit(ExitCodeCriteria)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// The environment variable to run a test as the subprocess exiting on failed criteria
+const criteriaTestExitEnv = "APING_CRITERIA_EXIT"
+
+// Create a flushed result of the successes and errors, all of the same latency
+func criteriaTestResult(successes int, errors int, latency time.Duration, violations map[string]int) Pongs {
+	result := Pongs{Histogram: newHistogram(), Successes: successes, Errors: errors, Violations: violations}
+	for i := 0; i < successes+errors; i++ {
+		result.Histogram.record(latency)
+	}
+	result.Latency = result.Histogram.getLatency()
+	return result
+}
+
+// Set the criteria flags for a test, returning the restore of the previous ones
+func setCriteriaTestFlags(maxErrorRate float64, maxP95 float64, maxTotalP95 float64, failUndocumented bool, failViolations bool) func() {
+	previousMaxErrorRate, previousMaxP95, previousMaxTotalP95 := *maxErrorRateFlag, *maxP95Flag, *maxTotalP95Flag
+	previousFailUndocumented, previousFailViolations := *failUndocumentedFlag, *failViolationsFlag
+	*maxErrorRateFlag, *maxP95Flag, *maxTotalP95Flag = maxErrorRate, maxP95, maxTotalP95
+	*failUndocumentedFlag, *failViolationsFlag = failUndocumented, failViolations
+	return func() {
+		*maxErrorRateFlag, *maxP95Flag, *maxTotalP95Flag = previousMaxErrorRate, previousMaxP95, previousMaxTotalP95
+		*failUndocumentedFlag, *failViolationsFlag = previousFailUndocumented, previousFailViolations
+	}
+}
+
+func TestEvaluateCriteria(t *testing.T) {
+	defer func(previous map[string]Pongs) {
+		Results = previous
+	}(Results)
+
+	Results = map[string]Pongs{
+		"GET /fast": criteriaTestResult(8, 0, 10*time.Millisecond, nil),
+		"GET /slow": criteriaTestResult(0, 2, 100*time.Millisecond, map[string]int{ViolationStatus: 1, ViolationSchema: 2}),
+	}
+	tests := []struct {
+		name             string
+		maxErrorRate     float64
+		maxP95           float64
+		maxTotalP95      float64
+		failUndocumented bool
+		failViolations   bool
+		expected         int
+	}{
+		{"none given", -1, -1, -1, false, false, 0},
+		{"error rate above", 10, -1, -1, false, false, 1},
+		{"error rate at limit", 20, -1, -1, false, false, 0},
+		{"p95 of one operation", -1, 50, -1, false, false, 1},
+		{"p95 of all operations", -1, -1, 50, false, false, 1},
+		{"p95 within", -1, 200, 200, false, false, 0},
+		{"undocumented", -1, -1, -1, true, false, 1},
+		{"violations", -1, -1, -1, false, true, 1},
+		{"all", 0, 0, 0, true, true, 6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer setCriteriaTestFlags(test.maxErrorRate, test.maxP95, test.maxTotalP95, test.failUndocumented, test.failViolations)()
+			if failures := evaluateCriteria(); len(failures) != test.expected {
+				t.Errorf("%d failures %v, expected %d", len(failures), failures, test.expected)
+			}
+		})
+	}
+}
+
+func TestCheckCriteriaExitCode(t *testing.T) {
+	// Check the criteria in a subprocess of this test, failing with errors only
+	if env := os.Getenv(criteriaTestExitEnv); env != "" {
+		errors := 0
+		if env == "fail" {
+			errors = 1
+		}
+		Results = map[string]Pongs{"GET /pets": criteriaTestResult(1, errors, time.Millisecond, nil)}
+		setCriteriaTestFlags(10, -1, -1, false, false)
+		checkCriteria()
+		return
+	}
+
+	tests := []struct {
+		name     string
+		env      string
+		expected int
+	}{
+		{"failed", "fail", ExitCodeCriteria},
+		{"passed", "pass", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestCheckCriteriaExitCode$")
+			cmd.Env = append(os.Environ(), criteriaTestExitEnv+"="+test.env)
+			err := cmd.Run()
+			exitCode := 0
+			if exitError, ok := err.(*exec.ExitError); ok {
+				exitCode = exitError.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if exitCode != test.expected {
+				t.Errorf("exited with %d, expected %d", exitCode, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Matching pattern for {$expressions} embedded in strings
+var regExEmbeddedExpressionPattern = regexp.MustCompile(`\{(\$[^}]+)\}`)
+
+// Variables extracted from responses, by name and by link target (operationId and parameter name or request body)
+type Variables struct {
+	mutex  sync.RWMutex
+	values map[string]interface{}
+	links  map[string]map[string]interface{}
+	bodies map[string]interface{}
+}
+
+// Create new empty variables
+func newVariables() *Variables {
+	return &Variables{
+		values: make(map[string]interface{}),
+		links:  make(map[string]map[string]interface{}),
+		bodies: make(map[string]interface{}),
+	}
+}
+
+// Set a named variable
+func (variables *Variables) set(name string, value interface{}) {
+	variables.mutex.Lock()
+	defer variables.mutex.Unlock()
+	variables.values[name] = value
+}
+
+// Set a link parameter value for the target operation
+func (variables *Variables) setLink(operationId string, name string, value interface{}) {
+	variables.mutex.Lock()
+	defer variables.mutex.Unlock()
+	if variables.links[operationId] == nil {
+		variables.links[operationId] = make(map[string]interface{})
+	}
+	variables.links[operationId][name] = value
+}
+
+// Set a link request body for the target operation
+func (variables *Variables) setLinkBody(operationId string, value interface{}) {
+	variables.mutex.Lock()
+	defer variables.mutex.Unlock()
+	variables.bodies[operationId] = value
+}
+
+// Get the link request body of the operation
+func (variables *Variables) getBody(operationId string) (interface{}, bool) {
+	if variables == nil || operationId == "" {
+		return nil, false
+	}
+	variables.mutex.RLock()
+	defer variables.mutex.RUnlock()
+	value, ok := variables.bodies[operationId]
+	return value, ok
+}
+
+// Get a parameter value by link to the operation first, otherwise by name
+func (variables *Variables) get(operationId string, name string) (interface{}, bool) {
+	if variables == nil {
+		return nil, false
+	}
+	variables.mutex.RLock()
+	defer variables.mutex.RUnlock()
+	if value, ok := variables.links[operationId][name]; ok && operationId != "" {
+		return value, true
+	}
+	value, ok := variables.values[name]
+	return value, ok
+}
+
+// Evaluate an OpenAPI runtime expression (e.g. "$response.body#/id" or "$response.header.Location"),
+// a JSONPath on the response body (e.g. "$.items[0].id"), a string with embedded {$expressions} or a constant
+func evaluateExpression(expression interface{}, pong *Pong) (interface{}, bool) {
+	text, isString := expression.(string)
+	if !isString {
+		return expression, true
+	}
+	if !strings.HasPrefix(text, "$") {
+		if !regExEmbeddedExpressionPattern.MatchString(text) {
+			return text, true
+		}
+		resolved := true
+		result := regExEmbeddedExpressionPattern.ReplaceAllStringFunc(text, func(match string) string {
+			value, ok := evaluateExpression(match[1:len(match)-1], pong)
+			resolved = resolved && ok
+			return formatValue(value)
+		})
+		return result, resolved
+	}
+
+	switch {
+	case text == "$url":
+		return pong.Ping.Url, true
+	case text == "$method":
+		return strings.ToUpper(pong.Ping.Method), true
+	case text == "$statusCode":
+		return pong.StatusCode, pong.StatusCode > 0
+	case strings.HasPrefix(text, "$."), strings.HasPrefix(text, "$["):
+		return evaluateJSONPath(pong.Body, text[1:])
+	case strings.HasPrefix(text, "$response.header."):
+		value := pong.Header.Get(strings.TrimPrefix(text, "$response.header."))
+		return value, value != ""
+	case strings.HasPrefix(text, "$response.body"):
+		return evaluateJSONPointer(pong.Body, strings.TrimPrefix(text, "$response.body"))
+	case strings.HasPrefix(text, "$request.header."):
+		name := strings.TrimPrefix(text, "$request.header.")
+		for key, value := range pong.Ping.Headers {
+			if strings.EqualFold(key, name) {
+				return value, true
+			}
+		}
+	case strings.HasPrefix(text, "$request.query."):
+		if requestUrl, err := url.Parse(pong.Ping.Url); err == nil {
+			values, ok := requestUrl.Query()[strings.TrimPrefix(text, "$request.query.")]
+			if ok && len(values) > 0 {
+				return values[0], true
+			}
+		}
+	case strings.HasPrefix(text, "$request.path."):
+		value, ok := pong.Ping.Parameters[strings.TrimPrefix(text, "$request.path.")]
+		return value, ok
+	case strings.HasPrefix(text, "$request.body"):
+		return evaluateJSONPointer(pong.Ping.Body, strings.TrimPrefix(text, "$request.body"))
+	}
+	return nil, false
+}
+
+// Evaluate a JSON pointer (e.g. "#/items/0/id") on a JSON body, an empty pointer is the whole body
+func evaluateJSONPointer(body []byte, pointer string) (interface{}, bool) {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, false
+	}
+	pointer = strings.TrimPrefix(pointer, "#")
+	if pointer == "" {
+		return document, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	segments := strings.Split(pointer[1:], "/")
+	for i, segment := range segments {
+		segments[i] = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+	}
+	return getJSONValue(document, segments)
+}
+
+// Evaluate a simple JSONPath (e.g. ".items[0].id" or "['key']") on a JSON body
+func evaluateJSONPath(body []byte, path string) (interface{}, bool) {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, false
+	}
+
+	segments := make([]string, 0)
+	for len(path) > 0 {
+		switch {
+		case path[0] == '.':
+			end := strings.IndexAny(path[1:], ".[")
+			if end < 0 {
+				end = len(path) - 1
+			}
+			segments = append(segments, path[1:end+1])
+			path = path[end+1:]
+		case path[0] == '[':
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, false
+			}
+			segments = append(segments, strings.Trim(path[1:end], "'\""))
+			path = path[end+1:]
+		default:
+			return nil, false
+		}
+	}
+	return getJSONValue(document, segments)
+}
+
+// Walk a decoded JSON document by object keys and array indices
+func getJSONValue(document interface{}, segments []string) (interface{}, bool) {
+	current := document
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// The JSON body of the expression tests
+const testExpressionBody = `{"id": 42, "name": "Rex", "tags": ["a", "b"], "owner": {"id": "o1"}, "a/b": 1, "m~n": 2, "": 3}`
+
+func TestEvaluateJSONPointer(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		pointer  string
+		expected interface{}
+		ok       bool
+	}{
+		{"whole body", `{"id": 1}`, "", map[string]interface{}{"id": 1.0}, true},
+		{"whole body hash", `[1]`, "#", []interface{}{1.0}, true},
+		{"property", testExpressionBody, "#/id", 42.0, true},
+		{"without hash", testExpressionBody, "/name", "Rex", true},
+		{"nested", testExpressionBody, "#/owner/id", "o1", true},
+		{"array index", testExpressionBody, "#/tags/1", "b", true},
+		{"escaped slash", testExpressionBody, "#/a~1b", 1.0, true},
+		{"escaped tilde", testExpressionBody, "#/m~0n", 2.0, true},
+		{"empty key", testExpressionBody, "#/", 3.0, true},
+		{"missing property", testExpressionBody, "#/missing", nil, false},
+		{"index out of range", testExpressionBody, "#/tags/2", nil, false},
+		{"index not a number", testExpressionBody, "#/tags/x", nil, false},
+		{"below primitive", testExpressionBody, "#/id/x", nil, false},
+		{"relative", testExpressionBody, "id", nil, false},
+		{"invalid body", `{`, "#/id", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, ok := evaluateJSONPointer([]byte(test.body), test.pointer)
+			if ok != test.ok || !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("%v (%t), expected %v (%t)", actual, ok, test.expected, test.ok)
+			}
+		})
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	pong := &Pong{
+		Ping: Ping{
+			Method:     "post",
+			Url:        "http://localhost/pets/7?limit=10&tag=a",
+			Headers:    map[string]string{"X-Request-Id": "r1"},
+			Body:       []byte(`{"name": "Rex"}`),
+			Parameters: map[string]interface{}{"petId": 7},
+		},
+		StatusCode: 201,
+		Header:     http.Header{"Location": []string{"/pets/42"}},
+		Body:       []byte(testExpressionBody),
+	}
+	tests := []struct {
+		name       string
+		expression interface{}
+		expected   interface{}
+		ok         bool
+	}{
+		{"constant", "fixed", "fixed", true},
+		{"non-string constant", 5.0, 5.0, true},
+		{"url", "$url", "http://localhost/pets/7?limit=10&tag=a", true},
+		{"method", "$method", "POST", true},
+		{"status code", "$statusCode", 201, true},
+		{"response header", "$response.header.Location", "/pets/42", true},
+		{"response header case", "$response.header.location", "/pets/42", true},
+		{"missing response header", "$response.header.X-Missing", "", false},
+		{"response body pointer", "$response.body#/owner/id", "o1", true},
+		{"request header", "$request.header.x-request-id", "r1", true},
+		{"missing request header", "$request.header.X-Missing", nil, false},
+		{"request query", "$request.query.limit", "10", true},
+		{"missing request query", "$request.query.offset", nil, false},
+		{"request path", "$request.path.petId", 7, true},
+		{"request body", "$request.body#/name", "Rex", true},
+		{"json path", "$.owner.id", "o1", true},
+		{"json path index", "$.tags[1]", "b", true},
+		{"json path bracket", "$['name']", "Rex", true},
+		{"embedded", "/owners/{$response.body#/owner/id}/pets/{$response.body#/id}", "/owners/o1/pets/42", true},
+		{"embedded missing", "/owners/{$response.body#/missing}", "/owners/", false},
+		{"unknown", "$unknown", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, ok := evaluateExpression(test.expression, pong)
+			if ok != test.ok || !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("%v (%t), expected %v (%t)", actual, ok, test.expected, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// The modes to pick from a list of fixture values
+const (
+	FixtureModeRoundRobin = "round-robin"
+	FixtureModeRandom     = "random"
+)
+
+// The loaded fixtures, if any
+var fixtures *Fixtures
+
+// All fixture values, by parameter name globally, per operationId and per path pattern
+type Fixtures struct {
+	Parameters map[string]*Fixture            `json:"parameters,omitempty"`
+	Operations map[string]map[string]*Fixture `json:"operations,omitempty"`
+	Paths      map[string]map[string]*Fixture `json:"paths,omitempty"`
+
+	pathPatterns []*PathPattern
+}
+
+// A fixed value, a list of values or a CSV column to pick parameter values from
+type Fixture struct {
+	// The round-robin index, first for a 64-bit alignment of atomic operations on 32-bit platforms
+	index uint64
+
+	Value  interface{}   `json:"value,omitempty"`
+	Values []interface{} `json:"values,omitempty"`
+	Csv    string        `json:"csv,omitempty"`
+	Column string        `json:"column,omitempty"`
+	Mode   string        `json:"mode,omitempty"`
+}
+
+// Any value besides an object with the fixture keys is a fixed value
+func (fixture *Fixture) UnmarshalJSON(data []byte) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err == nil {
+		_, hasValue := keys["value"]
+		_, hasValues := keys["values"]
+		_, hasCsv := keys["csv"]
+		if hasValue || hasValues || hasCsv {
+			type plainFixture Fixture
+			return json.Unmarshal(data, (*plainFixture)(fixture))
+		}
+	}
+	return json.Unmarshal(data, &fixture.Value)
+}
+
+// Parse any given parameter fixture file
+func parseFixtures() {
+	if paramsFlag == nil || *paramsFlag == "" {
+		return
+	}
+	var err error
+	fixtures, err = loadFixtures(*paramsFlag)
+	checkFatalError(err)
+}
+
+// Load a JSON or YAML fixture file and all referenced CSV files
+func loadFixtures(input string) (*Fixtures, error) {
+	result := &Fixtures{}
+	err := loadConfig(input, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// CSV files are relative to the fixture file
+	directory := ""
+	if _, isUrl := isValidUrl(input); !isUrl && input != StdinInput {
+		directory = filepath.Dir(input)
+	}
+	loadParameters := func(parameters map[string]*Fixture) error {
+		for name, fixture := range parameters {
+			if fixture == nil || fixture.Csv == "" {
+				continue
+			}
+			if err := fixture.loadCsv(directory, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err = loadParameters(result.Parameters); err != nil {
+		return nil, err
+	}
+	for _, parameters := range result.Operations {
+		if err = loadParameters(parameters); err != nil {
+			return nil, err
+		}
+	}
+
+	patterns := make([]string, 0, len(result.Paths))
+	for pattern, parameters := range result.Paths {
+		if err = loadParameters(parameters); err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	result.pathPatterns, err = compilePathPatterns(patterns)
+	return result, err
+}
+
+// Load all values of the CSV column, defaulting to the column named like the parameter
+func (fixture *Fixture) loadCsv(directory string, name string) error {
+	path := fixture.Csv
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(directory, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) < 2 {
+		return fmt.Errorf("the csv '%s' has no values", fixture.Csv)
+	}
+
+	column := fixture.Column
+	if column == "" {
+		column = name
+	}
+	index, found := contains(records[0], column)
+	if !found {
+		return fmt.Errorf("the csv '%s' has no column '%s'", fixture.Csv, column)
+	}
+	for _, record := range records[1:] {
+		if index < len(record) {
+			fixture.Values = append(fixture.Values, record[index])
+		}
+	}
+	return nil
+}
+
+// Get the fixture value of a parameter by operationId, path pattern or name (in this order)
+func getFixtureValue(random *rand.Rand, operationId string, path string, name string) (interface{}, bool) {
+	if fixtures == nil {
+		return nil, false
+	}
+	if fixture, ok := fixtures.Operations[operationId][name]; ok && operationId != "" && fixture != nil {
+		return fixture.next(random)
+	}
+	for _, pathPattern := range fixtures.pathPatterns {
+		if fixture, ok := fixtures.Paths[pathPattern.pattern][name]; ok && fixture != nil && pathPattern.regExp.MatchString(path) {
+			return fixture.next(random)
+		}
+	}
+	if fixture, ok := fixtures.Parameters[name]; ok && fixture != nil {
+		return fixture.next(random)
+	}
+	return nil, false
+}
+
+// Pick the fixed or next value, round-robin (default) or random
+func (fixture *Fixture) next(random *rand.Rand) (interface{}, bool) {
+	if len(fixture.Values) == 0 {
+		return fixture.Value, fixture.Value != nil
+	}
+	if fixture.Mode == FixtureModeRandom {
+		return fixture.Values[random.Intn(len(fixture.Values))], true
+	}
+	index := atomic.AddUint64(&fixture.index, 1) - 1
+	return fixture.Values[index%uint64(len(fixture.Values))], true
+}
+
+// Start all value lists from their beginning again
+func resetFixtures() {
+	if fixtures == nil {
+		return
+	}
+	reset := func(parameters map[string]*Fixture) {
+		for _, fixture := range parameters {
+			if fixture != nil {
+				atomic.StoreUint64(&fixture.index, 0)
+			}
+		}
+	}
+	reset(fixtures.Parameters)
+	for _, parameters := range fixtures.Operations {
+		reset(parameters)
+	}
+	for _, parameters := range fixtures.Paths {
+		reset(parameters)
+	}
+}
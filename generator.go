@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The maximum depth to generate nested (e.g. recursive) schemas to
+const generatorMaxDepth = 8
+
+// The maximum depth to generate optional properties to, keeping recursive schemas small
+const generatorOptionalDepth = 4
+
+// The maximum attempts to generate a pattern match within the length bounds
+const generatorPatternAttempts = 20
+
+// Generate a random value conforming to the given schema, preferring its example
+func generateValue(random *rand.Rand, schemaRef *openapi3.SchemaRef, depth int) (interface{}, bool) {
+	if schemaRef == nil || schemaRef.Value == nil || depth > generatorMaxDepth {
+		return nil, false
+	}
+	schema := schemaRef.Value
+
+	// Prefer the given samples
+	if schema.Example != nil {
+		return schema.Example, true
+	}
+	if schema.Default != nil {
+		return schema.Default, true
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[random.Intn(len(schema.Enum))], true
+	}
+
+	// Compositions
+	if len(schema.AllOf) > 0 {
+		return generateAllOf(random, schema, depth)
+	}
+	if len(schema.OneOf) > 0 {
+		return generateValue(random, schema.OneOf[random.Intn(len(schema.OneOf))], depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		return generateValue(random, schema.AnyOf[random.Intn(len(schema.AnyOf))], depth+1)
+	}
+
+	switch schema.Type {
+	case "object":
+		return generateObject(random, schema, depth)
+	case "array":
+		return generateArray(random, schema, depth)
+	case "integer":
+		return generateInteger(random, schema)
+	case "number":
+		return generateNumber(random, schema)
+	case "boolean":
+		return random.Intn(2) == 1, true
+	case "string":
+		return generateString(random, schema)
+	case "":
+		// Untyped schemas with properties are objects
+		if len(schema.Properties) > 0 {
+			return generateObject(random, schema, depth)
+		}
+		return generateString(random, schema)
+	}
+	return nil, false
+}
+
+// Merge all sub schemas into one value, objects are merged property by property
+func generateAllOf(random *rand.Rand, schema *openapi3.Schema, depth int) (interface{}, bool) {
+	var result interface{}
+	merged := make(map[string]interface{})
+	for _, subSchema := range schema.AllOf {
+		value, ok := generateValue(random, subSchema, depth+1)
+		if !ok {
+			return nil, false
+		}
+		if object, isObject := value.(map[string]interface{}); isObject {
+			for k, v := range object {
+				merged[k] = v
+			}
+			result = merged
+		} else if result == nil {
+			result = value
+		}
+	}
+	// Properties next to the allOf
+	if len(schema.Properties) > 0 {
+		object, ok := generateObject(random, schema, depth)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range object.(map[string]interface{}) {
+			merged[k] = v
+		}
+		result = merged
+	}
+	return result, result != nil
+}
+
+// Generate all properties of an object, skipping read-only and deeply nested optional ones.
+// Fails if a required property fails
+func generateObject(random *rand.Rand, schema *openapi3.Schema, depth int) (interface{}, bool) {
+	// Sort the properties for a stable generation order
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		property := schema.Properties[name]
+		_, isRequired := contains(schema.Required, name)
+		if !isRequired && (depth >= generatorOptionalDepth || property.Value != nil && property.Value.ReadOnly) {
+			continue
+		}
+		value, ok := generateValue(random, property, depth+1)
+		if !ok {
+			if isRequired {
+				return nil, false
+			}
+			continue
+		}
+		result[name] = value
+	}
+	return result, true
+}
+
+// Generate the minimum amount (at least one) of (unique) items
+func generateArray(random *rand.Rand, schema *openapi3.Schema, depth int) (interface{}, bool) {
+	length := 1
+	if schema.MinItems > 1 {
+		length = int(schema.MinItems)
+	}
+	if schema.MaxItems != nil && int(*schema.MaxItems) < length {
+		length = int(*schema.MaxItems)
+	}
+
+	result := make([]interface{}, 0, length)
+	for i, attempts := 0, 0; i < length; attempts++ {
+		value, ok := generateValue(random, schema.Items, depth+1)
+		if !ok {
+			return nil, false
+		}
+		// Retry duplicates a few times, small enums may not have enough values
+		if schema.UniqueItems && attempts < length*10 && containsValue(result, value) {
+			continue
+		}
+		result = append(result, value)
+		i++
+	}
+	return result, true
+}
+
+// Generate a random integer between the minimum (default 0) and maximum (default 100), respecting exclusive bounds and multiples.
+// Fails if the bounds exclude all integers
+func generateInteger(random *rand.Rand, schema *openapi3.Schema) (int64, bool) {
+	min, max := getRange(schema)
+	minInt, maxInt := int64(math.Ceil(min)), int64(math.Floor(max))
+	if schema.ExclusiveMin && float64(minInt) == min {
+		minInt++
+	}
+	if schema.ExclusiveMax && float64(maxInt) == max {
+		maxInt--
+	}
+	if minInt > maxInt {
+		return 0, false
+	}
+
+	if schema.MultipleOf != nil && *schema.MultipleOf >= 1 && *schema.MultipleOf == math.Trunc(*schema.MultipleOf) {
+		multipleOf := int64(*schema.MultipleOf)
+		minFactor := int64(math.Ceil(float64(minInt) / float64(multipleOf)))
+		maxFactor := int64(math.Floor(float64(maxInt) / float64(multipleOf)))
+		if minFactor <= maxFactor {
+			return (minFactor + random.Int63n(maxFactor-minFactor+1)) * multipleOf, true
+		}
+	}
+	return minInt + random.Int63n(maxInt-minInt+1), true
+}
+
+// Generate a random number between the minimum (default 0) and maximum (default 100), respecting exclusive bounds and multiples.
+// Fails if the exclusive bounds leave no number
+func generateNumber(random *rand.Rand, schema *openapi3.Schema) (float64, bool) {
+	min, max := getRange(schema)
+	if min == max && (schema.ExclusiveMin || schema.ExclusiveMax) {
+		return 0, false
+	}
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		multipleOf := *schema.MultipleOf
+		minFactor := math.Ceil(min / multipleOf)
+		maxFactor := math.Floor(max / multipleOf)
+		if schema.ExclusiveMin && minFactor*multipleOf == min {
+			minFactor++
+		}
+		if schema.ExclusiveMax && maxFactor*multipleOf == max {
+			maxFactor--
+		}
+		if minFactor <= maxFactor {
+			return (minFactor + float64(random.Int63n(int64(maxFactor-minFactor)+1))) * multipleOf, true
+		}
+	}
+
+	value := min + random.Float64()*(max-min)
+	// Float64 never returns 1, only the lower bound needs a nudge
+	if schema.ExclusiveMin && value == min {
+		value = math.Nextafter(min, max)
+	}
+	return value, true
+}
+
+// Get the minimum and maximum of a number schema, bounded by its format
+func getRange(schema *openapi3.Schema) (float64, float64) {
+	min := 0.0
+	max := 100.0
+	if schema.Min != nil {
+		min = *schema.Min
+	}
+	if schema.Max != nil {
+		max = *schema.Max
+	} else if min > max {
+		max = min + 100
+	}
+
+	switch schema.Format {
+	case "int32":
+		min = math.Max(min, math.MinInt32)
+		max = math.Min(max, math.MaxInt32)
+	case "int64":
+		// Keep within the exactly representable float range
+		min = math.Max(min, -(1 << 53))
+		max = math.Min(max, 1<<53)
+	}
+	if min > max {
+		min = max
+	}
+	return min, max
+}
+
+// Generate a random string by pattern, format or of the minimum (default 1) up to the maximum length.
+// Fails if no match of the pattern or no length is within the length bounds
+func generateString(random *rand.Rand, schema *openapi3.Schema) (interface{}, bool) {
+	if schema.Pattern != "" {
+		// Unsupported patterns fall back to the format or length
+		if value, ok := generatePattern(random, schema.Pattern); ok {
+			// Retry the random repetitions until a match is within the length bounds
+			for attempt := 1; !isValidLength(schema, value); attempt++ {
+				if attempt >= generatorPatternAttempts {
+					return nil, false
+				}
+				value, _ = generatePattern(random, schema.Pattern)
+			}
+			return value, true
+		}
+	}
+
+	switch schema.Format {
+	case "date":
+		return getRandTime(random).Format("2006-01-02"), true
+	case "date-time":
+		return getRandTime(random).Format(time.RFC3339), true
+	case "time":
+		return getRandTime(random).Format("15:04:05"), true
+	case "uuid":
+		return getRandUUID(random), true
+	case "email":
+		return fmt.Sprintf("%s@example.com", getRandString(random, 8)), true
+	case "hostname":
+		return fmt.Sprintf("%s.example.com", strings.ToLower(getRandString(random, 8))), true
+	case "uri", "url":
+		return fmt.Sprintf("https://example.com/%s", getRandString(random, 8)), true
+	case "ipv4":
+		return fmt.Sprintf("%d.%d.%d.%d", random.Intn(256), random.Intn(256), random.Intn(256), random.Intn(256)), true
+	case "ipv6":
+		return fmt.Sprintf("2001:db8::%x:%x", random.Intn(0x10000), random.Intn(0x10000)), true
+	case "byte":
+		return base64.StdEncoding.EncodeToString([]byte(getRandString(random, 8))), true
+	case "int32", "int64":
+		// Numbers transported as strings
+		value, ok := generateInteger(random, schema)
+		if !ok {
+			return nil, false
+		}
+		return strconv.FormatInt(value, 10), true
+	}
+
+	length := 1
+	if schema.MinLength > 1 {
+		length = int(schema.MinLength)
+	}
+	if schema.MaxLength != nil {
+		length = int(*schema.MaxLength)
+	}
+	if uint64(length) < schema.MinLength {
+		return nil, false
+	}
+	return getRandString(random, length), true
+}
+
+// Check if the length of the value in characters is within the length bounds of the schema
+func isValidLength(schema *openapi3.Schema, value string) bool {
+	length := uint64(utf8.RuneCountInString(value))
+	return length >= schema.MinLength && (schema.MaxLength == nil || length <= *schema.MaxLength)
+}
+
+// Get the first example (by name) with a value
+func getFirstExample(examples map[string]*openapi3.ExampleRef) (interface{}, bool) {
+	names := make([]string, 0, len(examples))
+	for name, example := range examples {
+		if example != nil && example.Value != nil && example.Value.Value != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, false
+	}
+	sort.Strings(names)
+	return examples[names[0]].Value.Value, true
+}
+
+// Check if a slice contains an equal value
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/getkin/kin-openapi v0.18.0
+	github.com/ghodss/yaml v1.0.0
 	github.com/go-openapi/strfmt v0.19.5 // indirect
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
 	github.com/mattn/go-runewidth v0.0.9 // indirect
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// The histogram resolution: 2^8 sub-buckets per power of two keep the relative error below 0.8%
+const histogramSubBucketBits = 8
+
+// The highest trackable value in microseconds (one hour), anything above is clamped
+const histogramMaxValue = int64(time.Hour / time.Microsecond)
+
+// An HDR-style log-linear histogram of latencies in microseconds with bounded memory
+type Histogram struct {
+	counts             []int64
+	subBucketHalfCount int64
+	subBucketMask      int64
+	totalCount         int64
+	min                int64
+	max                int64
+	sum                float64
+	sumOfSquares       float64
+}
+
+// Latency statistics in milliseconds
+type Latency struct {
+	Count  int64   `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	P999   float64 `json:"p99.9"`
+}
+
+// Create a new empty histogram
+func newHistogram() *Histogram {
+	subBucketCount := int64(1) << histogramSubBucketBits
+	histogram := &Histogram{
+		subBucketHalfCount: subBucketCount / 2,
+		subBucketMask:      subBucketCount - 1,
+		min:                math.MaxInt64,
+	}
+
+	// Enough buckets to cover the max value
+	bucketCount := 1
+	for smallestUntrackable := subBucketCount; smallestUntrackable <= histogramMaxValue; smallestUntrackable <<= 1 {
+		bucketCount++
+	}
+	histogram.counts = make([]int64, (bucketCount+1)*int(histogram.subBucketHalfCount))
+	return histogram
+}
+
+// Record a duration with microsecond resolution
+func (histogram *Histogram) record(duration time.Duration) {
+	value := int64(duration / time.Microsecond)
+	if value < 0 {
+		value = 0
+	} else if value > histogramMaxValue {
+		value = histogramMaxValue
+	}
+
+	histogram.counts[histogram.getCountsIndex(value)]++
+	histogram.totalCount++
+	if value < histogram.min {
+		histogram.min = value
+	}
+	if value > histogram.max {
+		histogram.max = value
+	}
+	histogram.sum += float64(value)
+	histogram.sumOfSquares += float64(value) * float64(value)
+}
+
+// Merge all recorded values of another histogram into this one
+func (histogram *Histogram) merge(other *Histogram) {
+	if other == nil || other.totalCount == 0 {
+		return
+	}
+	for i, count := range other.counts {
+		histogram.counts[i] += count
+	}
+	histogram.totalCount += other.totalCount
+	if other.min < histogram.min {
+		histogram.min = other.min
+	}
+	if other.max > histogram.max {
+		histogram.max = other.max
+	}
+	histogram.sum += other.sum
+	histogram.sumOfSquares += other.sumOfSquares
+}
+
+// Get the value at the given percentile (0-100) in microseconds
+func (histogram *Histogram) getValueAtPercentile(percentile float64) int64 {
+	if histogram.totalCount == 0 {
+		return 0
+	}
+	countAtPercentile := int64(math.Ceil(percentile / 100 * float64(histogram.totalCount)))
+	if countAtPercentile < 1 {
+		countAtPercentile = 1
+	}
+
+	var total int64
+	for i, count := range histogram.counts {
+		total += count
+		if total >= countAtPercentile {
+			value := histogram.getHighestEquivalentValue(i)
+			if value > histogram.max {
+				return histogram.max
+			}
+			return value
+		}
+	}
+	return histogram.max
+}
+
+// Summarize the histogram as latency statistics in milliseconds
+func (histogram *Histogram) getLatency() Latency {
+	if histogram.totalCount == 0 {
+		return Latency{}
+	}
+	count := float64(histogram.totalCount)
+	mean := histogram.sum / count
+	variance := histogram.sumOfSquares/count - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+
+	return Latency{
+		Count:  histogram.totalCount,
+		Min:    toMS(histogram.min),
+		Max:    toMS(histogram.max),
+		Mean:   mean / 1000,
+		StdDev: math.Sqrt(variance) / 1000,
+		P50:    toMS(histogram.getValueAtPercentile(50)),
+		P90:    toMS(histogram.getValueAtPercentile(90)),
+		P95:    toMS(histogram.getValueAtPercentile(95)),
+		P99:    toMS(histogram.getValueAtPercentile(99)),
+		P999:   toMS(histogram.getValueAtPercentile(99.9)),
+	}
+}
+
+// The counts index of a value
+func (histogram *Histogram) getCountsIndex(value int64) int {
+	bucketIndex := bits.Len64(uint64(value|histogram.subBucketMask)) - histogramSubBucketBits
+	subBucketIndex := value >> uint(bucketIndex)
+	return (bucketIndex+1)<<(histogramSubBucketBits-1) + int(subBucketIndex-histogram.subBucketHalfCount)
+}
+
+// The highest value sharing the counts index
+func (histogram *Histogram) getHighestEquivalentValue(index int) int64 {
+	bucketIndex := index>>(histogramSubBucketBits-1) - 1
+	subBucketIndex := int64(index)&(histogram.subBucketHalfCount-1) + histogram.subBucketHalfCount
+	if bucketIndex < 0 {
+		subBucketIndex -= histogram.subBucketHalfCount
+		bucketIndex = 0
+	}
+	return (subBucketIndex+1)<<uint(bucketIndex) - 1
+}
+
+// Microseconds to milliseconds
+func toMS(value int64) float64 {
+	return float64(value) / 1000
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// The relative error of the histogram resolution
+const histogramTestError = 0.008
+
+func TestHistogramPercentile(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []time.Duration
+		percentile float64
+		expected   int64
+	}{
+		{"empty", nil, 50, 0},
+		{"single", []time.Duration{5 * time.Millisecond}, 50, 5000},
+		{"single p100", []time.Duration{5 * time.Millisecond}, 100, 5000},
+		{"median", []time.Duration{1 * time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}, 50, 2000},
+		{"lowest", []time.Duration{1 * time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}, 0, 1000},
+		{"highest", []time.Duration{1 * time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}, 100, 3000},
+		{"small exact", []time.Duration{10 * time.Microsecond, 20 * time.Microsecond}, 100, 20},
+		{"negative clamped", []time.Duration{-time.Second}, 50, 0},
+		{"above max clamped", []time.Duration{2 * time.Hour}, 50, histogramMaxValue},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			histogram := newHistogram()
+			for _, value := range test.values {
+				histogram.record(value)
+			}
+			actual := histogram.getValueAtPercentile(test.percentile)
+			if math.Abs(float64(actual-test.expected)) > float64(test.expected)*histogramTestError {
+				t.Errorf("p%g = %d, expected %d", test.percentile, actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestHistogramLatency(t *testing.T) {
+	histogram := newHistogram()
+	for i := 1; i <= 100; i++ {
+		histogram.record(time.Duration(i) * time.Millisecond)
+	}
+	latency := histogram.getLatency()
+
+	tests := []struct {
+		name     string
+		actual   float64
+		expected float64
+	}{
+		{"min", latency.Min, 1},
+		{"max", latency.Max, 100},
+		{"mean", latency.Mean, 50.5},
+		{"stdDev", latency.StdDev, 28.866},
+		{"p50", latency.P50, 50},
+		{"p90", latency.P90, 90},
+		{"p95", latency.P95, 95},
+		{"p99", latency.P99, 99},
+		{"p99.9", latency.P999, 100},
+	}
+	if latency.Count != 100 {
+		t.Errorf("count = %d, expected 100", latency.Count)
+	}
+	for _, test := range tests {
+		if math.Abs(test.actual-test.expected) > test.expected*histogramTestError {
+			t.Errorf("%s = %.3f ms, expected %.3f ms", test.name, test.actual, test.expected)
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     []time.Duration
+		right    []time.Duration
+		count    int64
+		min      float64
+		max      float64
+		expected float64
+	}{
+		{"both", []time.Duration{time.Millisecond, 3 * time.Millisecond}, []time.Duration{2 * time.Millisecond, 4 * time.Millisecond}, 4, 1, 4, 2},
+		{"empty left", nil, []time.Duration{2 * time.Millisecond}, 1, 2, 2, 2},
+		{"empty right", []time.Duration{2 * time.Millisecond}, nil, 1, 2, 2, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			left, right := newHistogram(), newHistogram()
+			for _, value := range test.left {
+				left.record(value)
+			}
+			for _, value := range test.right {
+				right.record(value)
+			}
+			left.merge(right)
+			latency := left.getLatency()
+			if latency.Count != test.count || latency.Min != test.min || latency.Max != test.max {
+				t.Errorf("count/min/max = %d/%g/%g, expected %d/%g/%g", latency.Count, latency.Min, latency.Max, test.count, test.min, test.max)
+			}
+			if math.Abs(latency.P50-test.expected) > test.expected*histogramTestError {
+				t.Errorf("p50 = %g, expected %g", latency.P50, test.expected)
+			}
+		})
+	}
+
+	// Merging nothing changes nothing
+	histogram := newHistogram()
+	histogram.merge(nil)
+	if histogram.getLatency().Count != 0 {
+		t.Errorf("merging nil recorded values")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/ghodss/yaml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// The input value to read the document from stdin
+const StdinInput = "-"
+
+// The UTF-8 byte order mark some editors put in front of a document
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Load the input document from a file, url or stdin as OpenAPI 3.0, converting Swagger 2.0 if necessary
+func loadSwagger(input string) (*openapi3.Swagger, error) {
+	data, location, err := readInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Normalize YAML to JSON to sniff the version and feed the converter
+	data, err = toJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("the input '%s' is neither valid JSON nor YAML: %s", input, err)
+	}
+	swaggerOpenApi := SwaggerOpenApi{}
+	if err = json.Unmarshal(data, &swaggerOpenApi); err != nil {
+		return nil, err
+	}
+
+	//
+	if swaggerOpenApi.OpenAPI != "" && strings.HasPrefix(swaggerOpenApi.OpenAPI, "3") {
+		swaggerLoader := &openapi3.SwaggerLoader{
+			IsExternalRefsAllowed: true,
+		}
+		// Resolve relative references from the input location, if any
+		if location != nil {
+			return swaggerLoader.LoadSwaggerFromDataWithPath(data, location)
+		}
+		return swaggerLoader.LoadSwaggerFromData(data)
+	} else if swaggerOpenApi.Swagger != "" && strings.HasPrefix(swaggerOpenApi.Swagger, "2") {
+		// Convert Swagger 2.0 to OpenAPI 3.0 before pinging
+		return convertSwagger2(data)
+	}
+	return nil, fmt.Errorf("the input '%s' does not define its version as Swagger 2.0 or OpenAPI 3.0", input)
+}
+
+// Read the raw input and return its location to resolve relative references from (none for stdin)
+func readInput(input string) ([]byte, *url.URL, error) {
+	if input == StdinInput {
+		data, err := ioutil.ReadAll(os.Stdin)
+		return data, nil, err
+	}
+
+	if validUrl, isValid := isValidUrl(input); isValid {
+		response, err := http.Get(validUrl.String())
+		if err != nil {
+			return nil, nil, err
+		}
+		defer response.Body.Close()
+		if response.StatusCode >= http.StatusBadRequest {
+			return nil, nil, fmt.Errorf("the input '%s' could not be fetched: %s", input, response.Status)
+		}
+		data, err := ioutil.ReadAll(response.Body)
+		return data, validUrl, err
+	}
+
+	data, err := ioutil.ReadFile(input)
+	return data, &url.URL{Path: input}, err
+}
+
+// Load a JSON or YAML config from a file, url or stdin into the value
+func loadConfig(input string, v interface{}) error {
+	data, _, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	data, err = toJSON(data)
+	if err != nil {
+		return fmt.Errorf("the config '%s' is neither valid JSON nor YAML: %s", input, err)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Convert YAML to JSON by content, JSON is returned as is. Any leading byte order mark is removed
+func toJSON(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if isJSON(data) {
+		return data, nil
+	}
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	// Any scalar is valid YAML, but no document
+	if !isJSON(data) {
+		return nil, errors.New("no object found")
+	}
+	return data, nil
+}
+
+// Check if the data is a JSON object, ignoring any leading whitespace
+func isJSON(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
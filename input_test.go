@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]interface{}
+		ok       bool
+	}{
+		{"json", `{"openapi": "3.0.0"}`, map[string]interface{}{"openapi": "3.0.0"}, true},
+		{"json with whitespace", " \n\t{\"openapi\": \"3.0.0\"}", map[string]interface{}{"openapi": "3.0.0"}, true},
+		{"json with byte order mark", "\xef\xbb\xbf{\"openapi\": \"3.0.0\"}", map[string]interface{}{"openapi": "3.0.0"}, true},
+		{"yaml", "openapi: 3.0.0\ninfo:\n  title: Pets\n", map[string]interface{}{"openapi": "3.0.0", "info": map[string]interface{}{"title": "Pets"}}, true},
+		{"yaml with byte order mark", "\xef\xbb\xbfswagger: \"2.0\"\n", map[string]interface{}{"swagger": "2.0"}, true},
+		{"scalar", "just text", nil, false},
+		{"invalid yaml", "a: [b", nil, false},
+		{"empty", "", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := toJSON([]byte(test.data))
+			if (err == nil) != test.ok {
+				t.Fatalf("converted '%s' (%v), expected ok %t", data, err, test.ok)
+			}
+			if !test.ok {
+				return
+			}
+			actual := make(map[string]interface{})
+			if err = json.Unmarshal(data, &actual); err != nil {
+				t.Fatalf("'%s' is not valid JSON: %s", data, err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("%v, expected %v", actual, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The kinds of testcase failures
+const (
+	FailureError     = "error"
+	FailureStatus    = "status"
+	FailureViolation = "violation"
+	FailureSLO       = "slo"
+)
+
+// The suite name of the token endpoints
+const JUnitTokenSuite = "token endpoints"
+
+// The JUnit report of a run, one suite per tag or path
+type JUnitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*JUnitTestSuite `xml:"testsuite"`
+}
+
+// The operations of one tag or path
+type JUnitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr"`
+	Properties []JUnitProperty  `xml:"properties>property,omitempty"`
+	TestCases  []*JUnitTestCase `xml:"testcase"`
+	time       float64
+}
+
+// A property of a suite, e.g. the seed to replay the run
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// One operation
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// All problems of an operation, i.e. errors, undocumented status codes, violations and SLO breaches
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Render the results as JUnit XML, with a testsuite per (first) tag or path and a testcase per operation
+func renderJUnit(title string, date string) ([]byte, error) {
+	suites := make(map[string]*JUnitTestSuite)
+	addTestCase := func(suiteName string, result Pongs) {
+		suite, ok := suites[suiteName]
+		if !ok {
+			suite = &JUnitTestSuite{
+				Name:       suiteName,
+				Timestamp:  date,
+				Properties: []JUnitProperty{{Name: "seed", Value: strconv.FormatInt(seed, 10)}},
+			}
+			suites[suiteName] = suite
+		}
+		testCase := newJUnitTestCase(suiteName, result)
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+		suite.time += getTotalSeconds(result)
+	}
+	for _, key := range getSortedResultKeys() {
+		addTestCase(getJUnitSuiteName(Results[key]), Results[key])
+	}
+	for _, name := range getSortedTokenResultNames() {
+		addTestCase(JUnitTokenSuite, TokenResults[name])
+	}
+
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report := JUnitTestSuites{Name: title}
+	total := 0.0
+	for _, name := range names {
+		suite := suites[name]
+		suite.Time = formatSeconds(suite.time)
+		report.Suites = append(report.Suites, suite)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		total += suite.time
+	}
+	report.Time = formatSeconds(total)
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Create the testcase of an operation, failing on any problem
+func newJUnitTestCase(suiteName string, result Pongs) *JUnitTestCase {
+	name := getOperationKey(result.Method, result.Path)
+	if result.OperationId != "" {
+		name += " (" + result.OperationId + ")"
+	}
+	testCase := &JUnitTestCase{
+		Name:      name,
+		ClassName: suiteName,
+		Time:      formatSeconds(getTotalSeconds(result)),
+		SystemOut: fmt.Sprintf("%s\n%d / %d (ok / errors), p50 %s ms, p95 %s ms, p99 %s ms", strings.Join(result.Urls, "\n"), result.Successes, result.Errors, formatMS(result.Latency.P50), formatMS(result.Latency.P95), formatMS(result.Latency.P99)),
+	}
+
+	kinds := make([]string, 0)
+	problems := make([]string, 0)
+	if result.Errors > 0 {
+		kinds = append(kinds, FailureError)
+		problems = append(problems, fmt.Sprintf("%d of %d pings failed (%s)", result.Errors, result.Successes+result.Errors, formatOutcomes(result)))
+	}
+	if undocumented := result.Violations[ViolationStatus]; undocumented > 0 {
+		kinds = append(kinds, FailureStatus)
+		problems = append(problems, fmt.Sprintf("%d responses with an undocumented status code", undocumented))
+	}
+	if violations := countViolations(result) - result.Violations[ViolationStatus]; violations > 0 {
+		kinds = append(kinds, FailureViolation)
+		problems = append(problems, fmt.Sprintf("%d responses violating their content type or schema", violations))
+	}
+	if len(result.SLOBreaches) > 0 {
+		kinds = append(kinds, FailureSLO)
+		problems = append(problems, SLOBreached+" SLO: "+strings.Join(result.SLOBreaches, ", "))
+	}
+	if len(problems) > 0 {
+		lines := append(append([]string{}, problems...), result.ViolationMessages...)
+		testCase.Failure = &JUnitFailure{
+			Message: strings.Join(problems, "; "),
+			Type:    strings.Join(kinds, ", "),
+			Text:    strings.Join(lines, "\n"),
+		}
+	}
+	return testCase
+}
+
+// Get the suite of an operation, its first tag or else its path
+func getJUnitSuiteName(result Pongs) string {
+	if len(result.Tags) > 0 {
+		return result.Tags[0]
+	}
+	return result.Path
+}
+
+// Get the sum of all recorded latencies in seconds
+func getTotalSeconds(result Pongs) float64 {
+	return result.Latency.Mean * float64(result.Latency.Count) / 1000
+}
+
+// Format seconds with millisecond resolution
+func formatSeconds(value float64) string {
+	return fmt.Sprintf("%.3f", value)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestGetJUnitSuiteName(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Pongs
+		expected string
+	}{
+		{"first tag", Pongs{Path: "/pets", Tags: []string{"pets", "store"}}, "pets"},
+		{"path", Pongs{Path: "/pets/{id}"}, "/pets/{id}"},
+		{"empty tags", Pongs{Path: "/pets", Tags: []string{}}, "/pets"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := getJUnitSuiteName(test.result); actual != test.expected {
+				t.Errorf("'%s', expected '%s'", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestRenderJUnit(t *testing.T) {
+	defer func(previousResults map[string]Pongs, previousTokenResults map[string]Pongs) {
+		Results, TokenResults = previousResults, previousTokenResults
+	}(Results, TokenResults)
+
+	Results = map[string]Pongs{
+		"GET /pets":        {Path: "/pets", Method: "GET", Tags: []string{"pets"}, Successes: 2},
+		"POST /pets":       {Path: "/pets", Method: "POST", Tags: []string{"pets", "admin"}, Successes: 1, Errors: 1},
+		"GET /owners":      {Path: "/owners", Method: "GET", Successes: 1},
+		"GET /owners/{id}": {Path: "/owners/{id}", Method: "GET", Successes: 1, Violations: map[string]int{ViolationStatus: 1}},
+	}
+	TokenResults = map[string]Pongs{
+		"oauth": {Path: "https://auth.example.com/token", Method: "POST", Successes: 1},
+	}
+	data, err := renderJUnit("Pets", "2020-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := JUnitTestSuites{}
+	if err = xml.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		suite    string
+		cases    []string
+		failures int
+	}{
+		{"/owners", []string{"GET /owners"}, 0},
+		{"/owners/{id}", []string{"GET /owners/{id}"}, 1},
+		{"pets", []string{"GET /pets", "POST /pets"}, 1},
+		{JUnitTokenSuite, []string{"POST https://auth.example.com/token"}, 0},
+	}
+	if len(report.Suites) != len(tests) || report.Tests != 5 || report.Failures != 2 {
+		t.Fatalf("%d suites, %d tests and %d failures, expected %d, 5 and 2", len(report.Suites), report.Tests, report.Failures, len(tests))
+	}
+	for i, test := range tests {
+		suite := report.Suites[i]
+		cases := make([]string, len(suite.TestCases))
+		for j, testCase := range suite.TestCases {
+			cases[j] = testCase.Name
+		}
+		if suite.Name != test.suite || !reflect.DeepEqual(cases, test.cases) || suite.Failures != test.failures {
+			t.Errorf("suite '%s' with %v and %d failures, expected '%s' with %v and %d", suite.Name, cases, suite.Failures, test.suite, test.cases, test.failures)
+		}
+	}
+}
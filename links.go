@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Link expressions that could not be resolved, by consumer operationId and description
+var UnresolvedLinks = make(map[string]map[string]int)
+
+// Check if any response of the operation declares links
+func hasLinks(operation *Operation) bool {
+	return len(getLinkTargets(operation)) > 0
+}
+
+// Get the sorted operationIds all responses of the operation link to
+func getLinkTargets(operation *Operation) []string {
+	targets := make([]string, 0)
+	for _, response := range operation.Operation.Responses {
+		if response == nil || response.Value == nil {
+			continue
+		}
+		for _, link := range response.Value.Links {
+			if link == nil || link.Value == nil || link.Value.OperationID == "" {
+				continue
+			}
+			if _, found := contains(targets, link.Value.OperationID); !found {
+				targets = append(targets, link.Value.OperationID)
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Order the operations in levels, producers of links before their consumers.
+// Operations with cyclic links are pinged last
+func getOperationLevels(operations []Operation) [][]*Operation {
+	indices := make(map[string]int, len(operations))
+	for i, operation := range operations {
+		if operation.Operation.OperationID != "" {
+			indices[operation.Operation.OperationID] = i
+		}
+	}
+	// The producers of each consumer
+	producers := make([][]int, len(operations))
+	for i := range operations {
+		for _, target := range getLinkTargets(&operations[i]) {
+			if j, ok := indices[target]; ok && j != i {
+				producers[j] = append(producers[j], i)
+			}
+		}
+	}
+
+	levels := make([][]*Operation, 0, 1)
+	done := make([]bool, len(operations))
+	for remaining := len(operations); remaining > 0; {
+		level := make([]int, 0)
+		for i := range operations {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, producer := range producers[i] {
+				ready = ready && done[producer]
+			}
+			if ready {
+				level = append(level, i)
+			}
+		}
+		if len(level) == 0 {
+			cyclic := make([]string, 0)
+			for i := range operations {
+				if !done[i] {
+					level = append(level, i)
+					cyclic = append(cyclic, getOperationKey(operations[i].Method, operations[i].Path))
+				}
+			}
+			log.Println(fmt.Sprintf("[aPing] Cyclic links between '%s', pinging them last", strings.Join(cyclic, "', '")))
+		}
+
+		operationLevel := make([]*Operation, len(level))
+		for k, i := range level {
+			done[i] = true
+			operationLevel[k] = &operations[i]
+		}
+		levels = append(levels, operationLevel)
+		remaining -= len(level)
+	}
+	return levels
+}
+
+// Resolve the links of the response into the parameters and request bodies of their target operations.
+// Links that cannot be resolved are recorded for their target
+func applyLinks(operation *Operation, pong *Pong, variables *Variables) {
+	producer := getOperationKey(operation.Method, operation.Path)
+	links := getResponseLinks(operation, pong.StatusCode)
+	if len(links) == 0 {
+		// Without a linked response, e.g. on errors, no target gets its values
+		reason := pong.ErrorCategory
+		if pong.StatusCode > 0 {
+			reason = fmt.Sprintf("status %d", pong.StatusCode)
+		}
+		for _, target := range getLinkTargets(operation) {
+			recordUnresolvedLink(target, fmt.Sprintf("%s: no linked response (%s)", producer, reason))
+		}
+		return
+	}
+
+	for _, name := range getSortedLinkNames(links) {
+		link := links[name].Value
+		for _, parameter := range getSortedKeys(link.Parameters) {
+			value, ok := evaluateExpression(link.Parameters[parameter], pong)
+			if !ok {
+				recordUnresolvedLink(link.OperationID, fmt.Sprintf("%s %s: %s = %v", producer, name, parameter, link.Parameters[parameter]))
+				continue
+			}
+			variables.setLink(link.OperationID, getLinkParameterName(parameter), value)
+		}
+		if link.RequestBody != nil {
+			value, ok := evaluateExpression(link.RequestBody, pong)
+			if !ok {
+				recordUnresolvedLink(link.OperationID, fmt.Sprintf("%s %s: requestBody = %v", producer, name, link.RequestBody))
+				continue
+			}
+			variables.setLinkBody(link.OperationID, value)
+		}
+	}
+}
+
+// Replace the generated body by any linked request body, sent as JSON unless a text for a non-JSON content type
+func getLinkedBody(operation *Operation, variables *Variables, contentType string, body []byte) (string, []byte) {
+	value, ok := variables.getBody(operation.Operation.OperationID)
+	if !ok {
+		return contentType, body
+	}
+	if text, isText := value.(string); isText && contentType != "" && !isJSONContentType(contentType) {
+		return contentType, []byte(text)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return contentType, body
+	}
+	if !isJSONContentType(contentType) {
+		contentType = "application/json"
+	}
+	return contentType, data
+}
+
+// Get the links (with a target operationId) of the response matching the status code, or the default response
+func getResponseLinks(operation *Operation, statusCode int) map[string]*openapi3.LinkRef {
+	if operation.Operation.Responses == nil || statusCode <= 0 {
+		return nil
+	}
+	response := operation.Operation.Responses.Get(statusCode)
+	if response == nil {
+		response = operation.Operation.Responses.Default()
+	}
+	if response == nil || response.Value == nil {
+		return nil
+	}
+	links := make(map[string]*openapi3.LinkRef, len(response.Value.Links))
+	for name, link := range response.Value.Links {
+		if link != nil && link.Value != nil && link.Value.OperationID != "" {
+			links[name] = link
+		}
+	}
+	return links
+}
+
+// Get the link names sorted for a stable resolution order
+func getSortedLinkNames(links map[string]*openapi3.LinkRef) []string {
+	names := make([]string, 0, len(links))
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Strip any location qualifier of a link parameter, e.g. "path.id"
+func getLinkParameterName(parameter string) string {
+	for _, in := range []string{"path.", "query.", "header.", "cookie."} {
+		if strings.HasPrefix(parameter, in) {
+			return strings.TrimPrefix(parameter, in)
+		}
+	}
+	return parameter
+}
+
+// Count an unresolved link expression of the target operation
+func recordUnresolvedLink(operationId string, description string) {
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+	if UnresolvedLinks[operationId] == nil {
+		UnresolvedLinks[operationId] = make(map[string]int)
+	}
+	UnresolvedLinks[operationId][description]++
+}
+
+// Log all unresolved link expressions, by target operation
+func logUnresolvedLinks() {
+	operationIds := make([]string, 0, len(UnresolvedLinks))
+	for operationId := range UnresolvedLinks {
+		operationIds = append(operationIds, operationId)
+	}
+	sort.Strings(operationIds)
+	for _, operationId := range operationIds {
+		descriptions := make([]string, 0, len(UnresolvedLinks[operationId]))
+		for description, count := range UnresolvedLinks[operationId] {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%dx)", description, count))
+		}
+		sort.Strings(descriptions)
+		log.Println(fmt.Sprintf("[aPing] Unresolved links of '%s': %s", operationId, strings.Join(descriptions, "; ")))
+	}
+}
@@ -14,15 +14,17 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
 	"flag"
 	"fmt"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/jedib0t/go-pretty/progress"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -34,54 +36,42 @@ var client *http.Client
 // Matching pattern for {parameters} in paths
 var regExParameterPattern, _ = regexp.Compile("\\{.+\\}")
 
-//
 func main() {
 	// Parse the input arguments
 	flag.Parse()
 
 	// Parse the input file
 	if inputFlag == nil || *inputFlag != "" {
-		file, err := ioutil.ReadFile(*inputFlag)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		// Parse the json
-		swaggerOpenApi := SwaggerOpenApi{}
-		err = json.Unmarshal([]byte(file), &swaggerOpenApi)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		//
-		if swaggerOpenApi.OpenAPI != "" && strings.HasPrefix(swaggerOpenApi.OpenAPI, "3") {
-		} else if swaggerOpenApi.Swagger != "" && strings.HasPrefix(swaggerOpenApi.Swagger, "2") {
-		} else {
-			log.Fatal("The input file does not define its version as Swagger 2.0 or OpenAPI 3.0!", *inputFlag)
-		}
-
-		//
-		swaggerLoader := &openapi3.SwaggerLoader{
-			IsExternalRefsAllowed: true,
-		}
-		var swagger *openapi3.Swagger
-		if validUrl, isValid := isValidUrl(*inputFlag); isValid {
-			swagger, err = swaggerLoader.LoadSwaggerFromURI(validUrl)
-		} else {
-			swagger, err = swaggerLoader.LoadSwaggerFromFile(*inputFlag)
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
+		// Load the file, url or stdin input as OpenAPI 3.0
+		swagger, err := loadSwagger(*inputFlag)
+		checkFatalError(err)
 
 		// Parse any given header
 		parseHeader()
+		// Check for credentials of the security schemes
+		parseAuth(swagger)
 		// Check for a base path
 		parseBase(swagger)
 		// Check for methods to include
 		parseQueryMethods()
 		// Check for a path filter regular expression
 		parseFilter()
+		// Check for parameter fixtures
+		parseFixtures()
+		// Seed all random values
+		parseSeed()
+		// Check for pass/fail criteria
+		parseCriteria()
+		// Check for a login request
+		parseLogin()
+		// Check the output formats and files
+		parseOutputs(swagger)
+		// Check for a duration or request count to run for
+		parseBudget()
+		// Check for a load profile
+		parseStages()
+		// Check for a warm-up phase
+		parseWarmup()
 
 		//
 		var title string
@@ -91,70 +81,109 @@ func main() {
 			title = fmt.Sprintf("Pinging '%s - %s'", *inputFlag, *basePathFlag)
 		}
 		log.Println(title)
+		log.Println(fmt.Sprintf("Seed: %d", seed))
 
 		// Create a client with timeout and redirect handler
-		client = &http.Client{
-			Timeout: time.Second * time.Duration(*timeoutFlag),
-			// Pass the headers in case of redirects
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				for key, val := range via[0].Header {
-					req.Header[key] = val
-				}
-				return nil
-			},
-		}
+		client = newClient(nil)
+		// Create the sessions of all workers and log them in, if requested
+		parseSessions()
 
 		// Count all pingable routes for a correct output
+		operations := getOperations(swagger)
+		// Check for a scenario of chained operations
+		parseScenario(operations)
+		// Check for the SLOs of the operations
+		parseSLOs(operations)
+		// Check for request rates
+		parseRates(operations)
+		// Check for the weights of the traffic mix
+		parseWeights(operations)
 		var pings int
-		for path, pathItem := range swagger.Paths {
-			for method, operation := range pathItem.Operations() {
-				// Skip non-given methods
-				if _, isIncluded := contains(QueryMethods, method); !isIncluded {
-					continue
-				}
-				// Skip routes with request bodies (not supported)
-				if operation.RequestBody != nil && operation.RequestBody.Value.Required {
+		if scenario != nil {
+			// Every worker runs all steps of the scenario
+			pings = len(scenario.Steps) * *workerFlag
+		} else {
+			random := nextRand()
+			for _, operation := range operations {
+				// Skip routes with request bodies we cannot generate
+				if _, _, parsed := parseBody(random, operation.Operation); !parsed {
 					continue
 				}
-				// Skip routes we cannot parse (yet)
-				if _, parsed := parseUrl(path, operation); parsed {
+				// Skip routes we cannot parse (yet), without authenticating (and fetching tokens) just to count
+				if _, _, parsed := parseParameters(random, operation.Path, operation.PathItem, operation.Operation, nil); parsed {
 					pings++
 				}
 			}
 		}
 
+		// Counting took values from the fixture lists already
+		resetFixtures()
+
 		// Nothing to ping or loop
 		if pings <= 0 {
 			log.Fatal("[aPing] No pingable routes found/matches!")
 		}
-		if *loopFlag <= 0 {
+		if *loopFlag <= 0 && budget == nil {
 			log.Fatal("[aPing] No loops to run!")
 		}
-
-		// Set up the Progress Writer options
-		progressWriter.SetNumTrackersExpected(*loopFlag)
-		progressWriter.ShowOverallTracker(*loopFlag > 1)
-		progressWriter.SetTrackerLength(pings)
-		go progressWriter.Render()
-
-		// Prepare the progress trackers
-		progressTrackers := make([]progress.Tracker, *loopFlag)
-		for i := 0; i < *loopFlag; i++ {
-			progressTrackers[i] = progress.Tracker{Message: fmt.Sprintf("Pinging %d routes (Round %d)", pings, i+1), Total: int64(pings), Units: progress.UnitsDefault}
-			progressWriter.AppendTracker(&progressTrackers[i])
+		// Rates run for the pings of all loops, if no other budget is given
+		if hasRates() && budget == nil {
+			budget = &Budget{Requests: int64(pings * *loopFlag)}
 		}
-		// Start looping
-		for i := 0; i < *loopFlag; i++ {
-			loop(pings, swagger, &progressTrackers[i])
+
+		// Producers of links go first
+		levels := getOperationLevels(operations)
+		// Warm up the service, discarding the samples
+		warmup(pings, levels)
+		if budget != nil {
+			// Set up the Progress Writer options
+			progressWriter.SetNumTrackersExpected(budget.countTrackers())
+			progressWriter.ShowOverallTracker(false)
+			go progressWriter.Render()
+
+			// Keep pinging until the budget is exhausted
+			budget.begin()
+			if scenario != nil {
+				loopScenario(budget, budget.requestsTracker)
+			} else if hasRates() {
+				loopRate(pings, levels, budget, budget.requestsTracker)
+			} else {
+				loop(pings, levels, budget, budget.requestsTracker)
+			}
+			budget.end()
+		} else {
+			// Set up the Progress Writer options
+			progressWriter.SetNumTrackersExpected(*loopFlag)
+			progressWriter.ShowOverallTracker(*loopFlag > 1)
+			progressWriter.SetTrackerLength(pings)
+			go progressWriter.Render()
+
+			// Prepare the progress trackers
+			progressTrackers := make([]progress.Tracker, *loopFlag)
+			for i := 0; i < *loopFlag; i++ {
+				progressTrackers[i] = progress.Tracker{Message: fmt.Sprintf("Pinging %d routes (Round %d)", pings, i+1), Total: int64(pings), Units: progress.UnitsDefault}
+				progressWriter.AppendTracker(&progressTrackers[i])
+			}
+			// Start looping
+			for i := 0; i < *loopFlag; i++ {
+				if scenario != nil {
+					loopScenario(nil, &progressTrackers[i])
+				} else {
+					loop(pings, levels, nil, &progressTrackers[i])
+				}
+			}
 		}
 		// Wait for the progress writer to finish rendering
 		for progressWriter.IsRenderInProgress() {
 			time.Sleep(time.Millisecond * 100)
 		}
 		progressWriter.Stop()
+		logUnresolvedLinks()
 
 		// Flush the results
-		flush(title, outputFlag)
+		flush(title)
+		// Fail the run if any criterion is not met
+		checkCriteria()
 		return
 	}
 
@@ -162,120 +191,328 @@ func main() {
 	flag.Usage()
 }
 
-// Loop once through all paths
-func loop(pings int, swagger *openapi3.Swagger, progressTracker *progress.Tracker) {
+// Create a client with timeout and redirect handler, keeping cookies in the jar if given
+func newClient(jar http.CookieJar) *http.Client {
+	return &http.Client{
+		Timeout: time.Second * time.Duration(*timeoutFlag),
+		Jar:     jar,
+		// Pass the headers in case of redirects
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			for key, val := range via[0].Header {
+				req.Header[key] = val
+			}
+			return nil
+		},
+	}
+}
+
+// Get all operations of included methods, sorted by path and method for a stable order
+func getOperations(swagger *openapi3.Swagger) []Operation {
+	paths := make([]string, 0, len(swagger.Paths))
+	for path := range swagger.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	operations := make([]Operation, 0, len(paths))
+	for _, path := range paths {
+		pathItem := swagger.Paths[path]
+		methods := make([]string, 0)
+		for method := range pathItem.Operations() {
+			// Skip non-given methods
+			if _, isIncluded := contains(QueryMethods, method); isIncluded {
+				methods = append(methods, method)
+			}
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			operations = append(operations, Operation{
+				Path:      path,
+				Method:    method,
+				PathItem:  pathItem,
+				Operation: pathItem.GetOperation(method),
+			})
+		}
+	}
+	return operations
+}
+
+// Loop through all operations, once or round after round until the budget is exhausted
+func loop(pings int, levels [][]*Operation, budget *Budget, progressTracker *progress.Tracker) {
 	// Prepare the channels
 	var waitGroup sync.WaitGroup
 	jobs := make(chan *Ping, pings)
 
 	// Init some workers
+	var workers sync.WaitGroup
 	for worker := 0; worker < *workerFlag; worker++ {
-		go ping(jobs, &waitGroup, progressTracker)
+		workers.Add(1)
+		go func(session *Session) {
+			defer workers.Done()
+			ping(session, jobs, &waitGroup, progressTracker)
+		}(sessions[worker])
 	}
 
 	// Give the workers something to do (pingpong)
-	var ping *Ping
-	for path, pathItem := range swagger.Paths {
-		for method, operation := range pathItem.Operations() {
-			// Skip excluded methods
-			if _, isIncluded := contains(QueryMethods, method); !isIncluded {
-				continue
+	if hasWeights(budget) {
+		// The traffic mix picks the operations by weight instead of in rounds
+		operations := make([]*Operation, 0)
+		for _, level := range levels {
+			operations = append(operations, level...)
+		}
+		scheduleMix(newMix(operations), nextRand(), nil, budget, newVariables(), jobs, &waitGroup)
+	} else {
+		random := nextRand()
+		for {
+			// Stop if nothing could be pinged (anymore)
+			if queued := loopRound(levels, random, budget, jobs, &waitGroup); queued == 0 || budget.exhausted() {
+				break
 			}
-			// Skip routes with request bodies (not supported)
-			if operation.RequestBody != nil && operation.RequestBody.Value.Required {
+		}
+	}
+	// Release the workers
+	waitGroup.Wait()
+	close(jobs)
+	workers.Wait()
+}
+
+// Queue one round through all operations, level by level, so linked consumers get the values of their producers.
+// Returns the amount of queued pings
+func loopRound(levels [][]*Operation, random *rand.Rand, budget *Budget, jobs chan<- *Ping, waitGroup *sync.WaitGroup) int {
+	queued := 0
+	variables := newVariables()
+	for i, level := range levels {
+		for _, operation := range level {
+			// Skip routes with request bodies we cannot generate
+			contentType, body, parsed := parseBody(random, operation.Operation)
+			if !parsed {
 				continue
 			}
 			// Skip routes we cannot parse (yet)
-			if pathUrl, parsed := parseUrl(path, operation); parsed {
-				// Get a pool ping to reuse
-				ping = pingPool.Get().(*Ping)
-				ping.Method = method
-				ping.Path = path
-				ping.Url = pathUrl
-				ping.Headers = Headers
+			if request, parsed := parseRequest(random, operation.Path, operation.PathItem, operation.Operation, variables); parsed {
+				if !budget.take() {
+					return queued
+				}
+				contentType, body = getLinkedBody(operation, variables, contentType, body)
+				ping := newPing(operation, request, contentType, body)
+				if hasLinks(operation) {
+					ping.Capture = true
+					ping.variables = variables
+				}
 				// Fire
 				waitGroup.Add(1)
 				jobs <- ping
+				queued++
 			}
 		}
+		// Wait for all calls of the level to finish, before pinging their consumers.
+		// The next round does not depend on the last level
+		if i < len(levels)-1 {
+			waitGroup.Wait()
+		}
 	}
-	// Wait for all calls to finish
-	waitGroup.Wait()
+	return queued
 }
 
-// Ping the given url with all required headers and information
-func ping(pings <-chan *Ping, waitGroup *sync.WaitGroup, progressTracker *progress.Tracker) {
-	var pong *Pong
-	for ping := range pings {
-		// The response pool reset object
-		pong = pongPool.Get().(*Pong)
-		pong.Ping = *ping
-		pong.Response = "-"
-
-		methodName := strings.ToUpper(ping.Method)
-		req, err := http.NewRequest(methodName, ping.Url, nil)
-		if err != nil {
-			pong.Response = fmt.Sprintf("[aPing] The new HTTP request build failed with error: %s", err)
-		}
-		req.Close = true
+// Get a pool ping to reuse, filled with the operation, request and body
+func newPing(operation *Operation, request Request, contentType string, body []byte) *Ping {
+	ping := pingPool.Get().(*Ping)
+	ping.Method = operation.Method
+	ping.Path = operation.Path
+	ping.OperationId = operation.Operation.OperationID
+	ping.Url = request.Url
+	ping.Headers = mergeHeaders(Headers, request.Headers)
+	ping.Cookies = request.Cookies
+	ping.ContentType = contentType
+	ping.Body = body
+	ping.Parameters = request.Parameters
+	ping.Capture = *validateFlag
+	ping.operation = operation
+	ping.variables = nil
+	ping.intended = time.Time{}
+	return ping
+}
 
-		// Set headers
-		for key, value := range ping.Headers {
-			req.Header.Set(key, value)
-		}
+// Get a pool pong to reuse, reset for the given ping
+func newPong(ping *Ping) *Pong {
+	pong := pongPool.Get().(*Pong)
+	pong.Ping = *ping
+	pong.Time = 0
+	pong.Response = "-"
+	pong.StatusCode = 0
+	pong.StatusClass = ""
+	pong.ErrorCategory = ""
+	pong.Header = nil
+	pong.Body = nil
+	pong.Violations = nil
+	pong.Delay = 0
+	return pong
+}
 
-		// Fire & calculate elapsed ms
-		start := time.Now().UnixNano()
-		response, err := client.Do(req)
-		elapsed := getElapsedTimeInMS(start)
-		pong.Time = elapsed
+// Build and fire the request of the pong within the session
+func send(session *Session, pong *Pong) {
+	methodName := strings.ToUpper(pong.Ping.Method)
+	req, err := http.NewRequest(methodName, pong.Ping.Url, bytes.NewReader(pong.Ping.Body))
+	if err != nil {
+		pong.Response = fmt.Sprintf("[aPing] The new HTTP request build failed with error: %s", err)
+		pong.StatusClass = StatusClassError
+		pong.ErrorCategory = ErrorCategoryRequest
+		return
+	}
+	fire(session, req, pong)
+	if *validateFlag {
+		validatePong(pong)
+	}
+}
 
-		// Any error?
-		if err != nil {
-			pong.Response = fmt.Sprintf("[aPing] The HTTP request failed with error: %s", err)
-		} else {
-			if *responseFlag {
-				data, _ := ioutil.ReadAll(response.Body)
-				// Trim all line breaks from the response for better output
-				re := regexp.MustCompile(`\r?\n`)
-				bodyData := re.ReplaceAllString(string(data), " ")
-				// Store response
-				pong.Response = bodyData
-				_ = response.Body.Close()
-			}
+// Ping the given url with all required headers and information
+func ping(session *Session, pings <-chan *Ping, waitGroup *sync.WaitGroup, progressTracker *progress.Tracker) {
+	for {
+		// Inactive workers of a stage wait for their turn
+		waitForStage(session)
+		ping, ok := <-pings
+		if !ok {
+			return
+		}
+		// The response pool reset object
+		pong := newPong(ping)
+		send(session, pong)
+		// Producers resolve the link values of their consumers
+		if ping.variables != nil {
+			applyLinks(ping.operation, pong, ping.variables)
 		}
 
 		// Collect the pongs
 		collectPong(pong)
 
 		// Clear & Count up
-		progressTracker.Increment(1)
+		if progressTracker != nil {
+			progressTracker.Increment(1)
+		}
 		waitGroup.Done()
 		// Return to the source Neo
 		pingPool.Put(ping)
 	}
 }
 
+// Fire the request within the session and record time, status and (optionally) the response body
+func fire(session *Session, req *http.Request, pong *Pong) {
+	req.Close = true
+
+	// Set headers, the login ones last
+	for key, value := range pong.Ping.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range session.headers {
+		req.Header.Set(key, value)
+	}
+	for name, value := range pong.Ping.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	// The body decides about its content type
+	if pong.Ping.ContentType != "" {
+		req.Header.Set("Content-Type", pong.Ping.ContentType)
+	}
+
+	// Fire & measure the elapsed time, and any delay behind the timeline of a rate
+	start := time.Now()
+	if !pong.Ping.intended.IsZero() {
+		pong.Delay = start.Sub(pong.Ping.intended)
+	}
+	response, err := session.client.Do(req)
+	pong.Time = time.Since(start)
+
+	// Any error?
+	if err != nil {
+		pong.Response = fmt.Sprintf("[aPing] The HTTP request failed with error: %s", err)
+		pong.StatusClass = StatusClassError
+		pong.ErrorCategory = getErrorCategory(err)
+		return
+	}
+	defer response.Body.Close()
+
+	pong.StatusCode = response.StatusCode
+	pong.StatusClass = getStatusClass(response.StatusCode)
+	if *responseFlag || pong.Ping.Capture {
+		data, _ := ioutil.ReadAll(response.Body)
+		if pong.Ping.Capture {
+			pong.Header = response.Header
+			pong.Body = data
+		}
+		if *responseFlag {
+			// Trim all line breaks from the response for better output
+			re := regexp.MustCompile(`\r?\n`)
+			bodyData := re.ReplaceAllString(string(data), " ")
+			// Store response
+			pong.Response = bodyData
+		}
+	}
+}
+
 // Collect and merge/average all
 func collectPong(pong *Pong) {
-	// Ignore pongs above the threshold
-	if *thresholdFlag < 0 || pong.Time >= int64(*thresholdFlag) {
-		//
-		p, ok := Results[pong.Ping.Path]
-		if !ok {
-			p = Pongs{
-				Path:   pong.Ping.Path,
-				Method: pong.Ping.Method,
-			}
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+
+	// Each operation (method + path) gets its own statistics
+	key := getOperationKey(pong.Ping.Method, pong.Ping.Path)
+	if warmingUp {
+		WarmupResults[key] = recordPong(WarmupResults[key], pong)
+	} else {
+		Results[key] = recordPong(Results[key], pong)
+		recordStagePong(pong)
+	}
+
+	// Return to the source Neo
+	pongPool.Put(pong)
+}
+
+// Record the pong in the statistics of its operation, created on the first pong
+func recordPong(p Pongs, pong *Pong) Pongs {
+	if p.Histogram == nil {
+		p = Pongs{
+			Path:            pong.Ping.Path,
+			Method:          pong.Ping.Method,
+			OperationId:     pong.Ping.OperationId,
+			Histogram:       newHistogram(),
+			StatusCodes:     make(map[int]int),
+			ErrorCategories: make(map[string]int),
 		}
+		if pong.Ping.operation != nil {
+			p.Tags = pong.Ping.operation.Operation.Tags
+		}
+	}
+
+	// Count all outcomes, even fast ones, to not hide any errors
+	if pong.StatusCode > 0 {
+		p.StatusCodes[pong.StatusCode]++
+	}
+	if pong.ErrorCategory != "" {
+		p.ErrorCategories[pong.ErrorCategory]++
+	}
+	if isSuccess(pong) {
+		p.Successes++
+	} else {
+		p.Errors++
+	}
+	recordViolations(&p, pong.Violations)
+
+	// Ignore pongs above the threshold
+	if *thresholdFlag < 0 || pong.Time >= time.Duration(*thresholdFlag)*time.Millisecond {
 		if p.Urls == nil || regExParameterPattern.Match([]byte(pong.Ping.Path)) {
 			p.Urls = append(p.Urls, pong.Ping.Url)
 			p.Responses = append(p.Responses, pong.Response)
 		}
-		p.Time += pong.Time
-		Results[pong.Ping.Path] = p
+		p.Histogram.record(pong.Time)
+		// Pings of a rate count from their intended start
+		if !pong.Ping.intended.IsZero() {
+			if p.CorrectedHistogram == nil {
+				p.CorrectedHistogram = newHistogram()
+				p.DelayHistogram = newHistogram()
+			}
+			p.CorrectedHistogram.record(pong.Delay + pong.Time)
+			p.DelayHistogram.record(pong.Delay)
+		}
 	}
-
-	// Return to the source Neo
-	pongPool.Put(pong)
+	return p
 }
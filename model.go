@@ -1,37 +1,124 @@
 package main
 
 import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 // A single entry to "ping"
 type Ping struct {
-	Method  string            `json:"method"`
-	Path    string            `json:"path"`
-	Url     string            `json:"url"`
-	Headers map[string]string `json:"headers"`
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	OperationId string            `json:"operationId,omitempty"`
+	Url         string            `json:"url"`
+	Headers     map[string]string `json:"headers"`
+	Cookies     map[string]string `json:"cookies,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	// The parameter values by name, e.g. for "$request.path.id" expressions
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Keep the response headers and body to extract variables from
+	Capture bool `json:"-"`
+	// The operation to validate the response against and resolve the links of into the variables of the round
+	operation *Operation
+	variables *Variables
+	// The start on the arrival timeline of a rate, if any
+	intended time.Time
+}
+
+// An operation of the spec to ping
+type Operation struct {
+	Path      string
+	Method    string
+	PathItem  *openapi3.PathItem
+	Operation *openapi3.Operation
+}
+
+// A request with all generated parameters in place
+type Request struct {
+	Url        string
+	Headers    map[string]string
+	Cookies    map[string]string
+	Parameters map[string]interface{}
 }
 
 // A response
 type Pong struct {
-	Ping     Ping   `json:"ping"`
-	Time     int64  `json:"time"`
-	Response string `json:"response"`
+	Ping          Ping          `json:"ping"`
+	Time          time.Duration `json:"time"`
+	StatusCode    int           `json:"statusCode"`
+	StatusClass   string        `json:"statusClass"`
+	ErrorCategory string        `json:"errorCategory,omitempty"`
+	Response      string        `json:"response"`
+	// The captured response, if requested by the ping
+	Header http.Header `json:"-"`
+	Body   []byte      `json:"-"`
+	// Violations of the response against the spec
+	Violations []Violation `json:"violations,omitempty"`
+	// The delay of the actual start behind the intended one of a rate
+	Delay time.Duration `json:"delay,omitempty"`
 }
 
-// All responses
+// All responses of one operation
 type Pongs struct {
-	Path      string   `json:"path"`
-	Method    string   `json:"method"`
-	Time      int64    `json:"time"`
-	Urls      []string `json:"urls"`
-	Responses []string `json:"responses"`
+	Path        string   `json:"path"`
+	Method      string   `json:"method"`
+	OperationId string   `json:"operationId,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Urls        []string `json:"urls"`
+	Responses   []string `json:"responses"`
+	// Latencies
+	Histogram *Histogram `json:"-"`
+	Latency   Latency    `json:"latency"`
+	// Latencies from the intended start of a rate, corrected for coordinated omission, and the start delays
+	CorrectedHistogram *Histogram `json:"-"`
+	Corrected          *Latency   `json:"corrected,omitempty"`
+	DelayHistogram     *Histogram `json:"-"`
+	Delay              *Latency   `json:"delay,omitempty"`
+	// Outcomes
+	StatusCodes     map[int]int    `json:"statusCodes"`
+	ErrorCategories map[string]int `json:"errorCategories"`
+	Successes       int            `json:"successes"`
+	Errors          int            `json:"errors"`
+	// Link expressions of producers that could not be resolved for this operation
+	UnresolvedLinks map[string]int `json:"unresolvedLinks,omitempty"`
+	// Response violations by kind and the first messages
+	Violations        map[string]int `json:"violations,omitempty"`
+	ViolationMessages []string       `json:"violationMessages,omitempty"`
+	// The SLO of the operation and its breaches
+	SLO         *SLO     `json:"slo,omitempty"`
+	SLOBreaches []string `json:"sloBreaches,omitempty"`
+}
+
+// An output format and the file to write it to, "-" for stdout
+type Output struct {
+	Format string
+	File   string
+}
+
+// The JSON report of a run
+type Report struct {
+	Title   string           `json:"title"`
+	Date    string           `json:"date"`
+	Seed    int64            `json:"seed"`
+	Results map[string]Pongs `json:"results"`
+	Tokens  map[string]Pongs `json:"tokens,omitempty"`
+	Stages  []*StageResult   `json:"stages,omitempty"`
+	Warmup  map[string]Pongs `json:"warmup,omitempty"`
 }
 
 // Pre-parse the input to see if it is an openapi 3.0 or swagger 2.0 file
 type SwaggerOpenApi struct {
-	Swagger string `json:"swagger;omitempty"`
-	OpenAPI string `json:"openapi;omitempty"`
+	Swagger string `json:"swagger,omitempty"`
+	OpenAPI string `json:"openapi,omitempty"`
+}
+
+// Swagger 2.0 global request media types, which the converter does not know about
+type Swagger2MediaTypes struct {
+	Consumes []string `json:"consumes,omitempty"`
 }
 
 // The default request headers
@@ -56,5 +143,13 @@ var pongPool = sync.Pool{
 	},
 }
 
-// All collected Pongs
+// All collected Pongs by operation key
 var Results = make(map[string]Pongs)
+
+// Guard the results against concurrent workers
+var resultsMutex sync.Mutex
+
+// The unique key of an operation, e.g. "GET /users/{id}"
+func getOperationKey(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
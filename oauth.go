@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The OAuth2 grant types to fetch tokens with
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypePassword          = "password"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// Refresh tokens this long before they expire, at most a tenth of their lifetime
+const tokenRefreshMargin = 30 * time.Second
+
+// Wait this long before fetching a token again after a failure, doubled per failure up to the maximum
+const (
+	tokenRetryBackoff    = time.Second
+	tokenRetryMaxBackoff = 30 * time.Second
+)
+
+// The cached tokens by scheme name and scope set
+var (
+	tokens      = make(map[string]*TokenCache)
+	tokensMutex sync.Mutex
+)
+
+// All collected token endpoint pongs by scheme name, separate from the API results
+var TokenResults = make(map[string]Pongs)
+
+// The cached token of a scheme and scope set, locked while fetching it.
+// Failed fetches are not repeated before the retry time
+type TokenCache struct {
+	mutex    sync.Mutex
+	token    *OAuthToken
+	failures int
+	retryAt  time.Time
+}
+
+// A fetched access token and when to refresh it, never if zero
+type OAuthToken struct {
+	AccessToken  string
+	RefreshToken string
+	RefreshAt    time.Time
+}
+
+// The token endpoint response
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// Check if a token can be fetched for the scheme, i.e. a client and a client credentials or password flow
+func isOAuthFetchable(scheme *openapi3.SecurityScheme, credential *Credential) bool {
+	if scheme.Type != SecuritySchemeOAuth2 || credential.ClientId == "" {
+		return false
+	}
+	_, flow := getOAuthFlow(scheme, credential)
+	return flow != nil && getTokenUrl(flow, credential) != ""
+}
+
+// Get the grant type and flow to fetch a token with, the password flow if a username is given
+func getOAuthFlow(scheme *openapi3.SecurityScheme, credential *Credential) (string, *openapi3.OAuthFlow) {
+	if scheme.Flows == nil {
+		return "", nil
+	}
+	if credential.Username != "" && scheme.Flows.Password != nil {
+		return GrantTypePassword, scheme.Flows.Password
+	}
+	if scheme.Flows.ClientCredentials != nil {
+		return GrantTypeClientCredentials, scheme.Flows.ClientCredentials
+	}
+	return "", nil
+}
+
+// Get the token url of the flow, overridden by the credential and relative ones resolved against the base url
+func getTokenUrl(flow *openapi3.OAuthFlow, credential *Credential) string {
+	tokenUrl := flow.TokenURL
+	if credential.TokenUrl != "" {
+		tokenUrl = credential.TokenUrl
+	}
+	if tokenUrl != "" {
+		if _, ok := isValidUrl(tokenUrl); !ok {
+			tokenUrl = strings.TrimRight(basePath, "/") + "/" + strings.TrimLeft(tokenUrl, "/")
+		}
+	}
+	return tokenUrl
+}
+
+// Get a cached token of the scheme and scopes, fetching or refreshing it if due
+func getOAuthToken(name string, scheme *openapi3.SecurityScheme, credential *Credential, scopes []string) (string, bool) {
+	sortedScopes := append([]string{}, scopes...)
+	sort.Strings(sortedScopes)
+	key := name + " " + strings.Join(sortedScopes, " ")
+
+	// Only pings of the same scheme and scopes wait for a fetch
+	cache := getTokenCache(key)
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	token, ok := cache.token, cache.token != nil
+	if ok && (token.RefreshAt.IsZero() || time.Now().Before(token.RefreshAt)) {
+		return token.AccessToken, true
+	}
+	// Pings waiting for a failed fetch go on without a token instead of fetching one after the other
+	if time.Now().Before(cache.retryAt) {
+		return "", false
+	}
+
+	grantType, flow := getOAuthFlow(scheme, credential)
+	var fetched *OAuthToken
+	fetchedOk := false
+	if ok && token.RefreshToken != "" {
+		refreshUrl := getTokenUrl(flow, credential)
+		if flow.RefreshURL != "" && credential.TokenUrl == "" {
+			refreshUrl = flow.RefreshURL
+		}
+		fetched, fetchedOk = fetchOAuthToken(name, refreshUrl, getOAuthForm(GrantTypeRefreshToken, credential, token.RefreshToken, sortedScopes), credential)
+	}
+	// Refresh tokens may be missing, expired or revoked, start over
+	if !fetchedOk {
+		fetched, fetchedOk = fetchOAuthToken(name, getTokenUrl(flow, credential), getOAuthForm(grantType, credential, "", sortedScopes), credential)
+	}
+	if !fetchedOk {
+		cache.retryAt = time.Now().Add(getTokenBackoff(cache.failures))
+		cache.failures++
+		return "", false
+	}
+	cache.token, cache.failures, cache.retryAt = fetched, 0, time.Time{}
+	return fetched.AccessToken, true
+}
+
+// Get the backoff after the given number of previous failures
+func getTokenBackoff(failures int) time.Duration {
+	backoff := tokenRetryBackoff
+	for i := 0; i < failures && backoff < tokenRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > tokenRetryMaxBackoff {
+		backoff = tokenRetryMaxBackoff
+	}
+	return backoff
+}
+
+// Get the token cache of the scheme and scopes key, created on first use
+func getTokenCache(key string) *TokenCache {
+	tokensMutex.Lock()
+	defer tokensMutex.Unlock()
+	cache, ok := tokens[key]
+	if !ok {
+		cache = &TokenCache{}
+		tokens[key] = cache
+	}
+	return cache
+}
+
+// Get the token request form of the grant type
+func getOAuthForm(grantType string, credential *Credential, refreshToken string, scopes []string) url.Values {
+	form := url.Values{}
+	form.Set("grant_type", grantType)
+	switch grantType {
+	case GrantTypePassword:
+		form.Set("username", credential.Username)
+		form.Set("password", credential.Password)
+	case GrantTypeRefreshToken:
+		form.Set("refresh_token", refreshToken)
+	}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+	// Public clients identify in the form, confidential ones by basic auth
+	if credential.ClientSecret == "" {
+		form.Set("client_id", credential.ClientId)
+	}
+	return form
+}
+
+// Request a token from the endpoint, recording the token pong
+func fetchOAuthToken(name string, tokenUrl string, form url.Values, credential *Credential) (*OAuthToken, bool) {
+	ping := &Ping{
+		Method:      http.MethodPost,
+		Path:        tokenUrl,
+		OperationId: "token: " + name,
+		Url:         tokenUrl,
+		ContentType: "application/x-www-form-urlencoded",
+		Body:        []byte(form.Encode()),
+	}
+	pong := &Pong{Ping: *ping, Response: "-"}
+	defer collectTokenPong(name, pong)
+
+	req, err := http.NewRequest(ping.Method, ping.Url, strings.NewReader(form.Encode()))
+	if err != nil {
+		pong.Response = fmt.Sprintf("[aPing] The token request build failed with error: %s", err)
+		pong.StatusClass = StatusClassError
+		pong.ErrorCategory = ErrorCategoryRequest
+		return nil, false
+	}
+	req.Header.Set("Content-Type", ping.ContentType)
+	req.Header.Set("Accept", "application/json")
+	if credential.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(credential.ClientId), url.QueryEscape(credential.ClientSecret))
+	}
+
+	start := time.Now()
+	response, err := client.Do(req)
+	pong.Time = time.Since(start)
+	if err != nil {
+		pong.Response = fmt.Sprintf("[aPing] The token request failed with error: %s", err)
+		pong.StatusClass = StatusClassError
+		pong.ErrorCategory = getErrorCategory(err)
+		return nil, false
+	}
+	defer response.Body.Close()
+
+	pong.StatusCode = response.StatusCode
+	pong.StatusClass = getStatusClass(response.StatusCode)
+	// The response is not recorded, it contains the token
+	data, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode >= 400 {
+		return nil, false
+	}
+
+	tokenResponse := OAuthTokenResponse{}
+	if err = json.Unmarshal(data, &tokenResponse); err != nil || tokenResponse.AccessToken == "" {
+		return nil, false
+	}
+	// Tokens without expiry are kept for the run
+	token := &OAuthToken{
+		AccessToken:  tokenResponse.AccessToken,
+		RefreshToken: tokenResponse.RefreshToken,
+	}
+	if tokenResponse.ExpiresIn > 0 {
+		lifetime := time.Duration(tokenResponse.ExpiresIn) * time.Second
+		margin := tokenRefreshMargin
+		if margin > lifetime/10 {
+			margin = lifetime / 10
+		}
+		token.RefreshAt = start.Add(lifetime - margin)
+	}
+	return token, true
+}
+
+// Collect a token endpoint pong, separate from the API results
+func collectTokenPong(name string, pong *Pong) {
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+	TokenResults[name] = recordPong(TokenResults[name], pong)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetTokenBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{5, tokenRetryMaxBackoff},
+		{100, tokenRetryMaxBackoff},
+	}
+	for _, test := range tests {
+		if actual := getTokenBackoff(test.failures); actual != test.expected {
+			t.Errorf("%d failures back off %s, expected %s", test.failures, actual, test.expected)
+		}
+	}
+}
+
+func TestGetOAuthTokenBackoff(t *testing.T) {
+	defer func(previousClient *http.Client, previousTokens map[string]*TokenCache, previousResults map[string]Pongs) {
+		client, tokens, TokenResults = previousClient, previousTokens, previousResults
+	}(client, tokens, TokenResults)
+
+	var fetches int32
+	var failing int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "abc", "token_type": "bearer"}`))
+	}))
+	defer server.Close()
+
+	client, tokens, TokenResults = server.Client(), make(map[string]*TokenCache), make(map[string]Pongs)
+	scheme := &openapi3.SecurityScheme{
+		Type:  SecuritySchemeOAuth2,
+		Flows: &openapi3.OAuthFlows{ClientCredentials: &openapi3.OAuthFlow{TokenURL: server.URL}},
+	}
+	credential := &Credential{ClientId: "aping"}
+
+	// Pings waiting for the failed fetch do not fetch again
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if _, ok := getOAuthToken("oauth", scheme, credential, nil); ok {
+				t.Errorf("got a token of a failing endpoint")
+			}
+		}()
+	}
+	waitGroup.Wait()
+	if fetches != 1 {
+		t.Errorf("%d fetches within the backoff, expected 1", fetches)
+	}
+
+	// After the backoff, the token is fetched again
+	atomic.StoreInt32(&failing, 0)
+	tokens["oauth "].retryAt = time.Now().Add(-time.Millisecond)
+	if token, ok := getOAuthToken("oauth", scheme, credential, nil); !ok || token != "abc" {
+		t.Errorf("token '%s' (%t) after the backoff, expected 'abc'", token, ok)
+	}
+	if fetches != 2 || tokens["oauth "].failures != 0 {
+		t.Errorf("%d fetches and %d failures, expected 2 and none", fetches, tokens["oauth "].failures)
+	}
+}
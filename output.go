@@ -2,9 +2,17 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/jedib0t/go-pretty/table"
+	"github.com/jedib0t/go-pretty/text"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -64,11 +72,15 @@ var HtmlTemplate = `
 <body>
   <h2>aPing - Results</h2>
   <h4>{{TITLE}} @ {{DATE}}</h4>
+  <h6>Seed: {{SEED}}</h6>
 
   <div class="container-fluid">
     <div class="row">
       {{TABLE}}
     </div>
+    <div class="row">
+      {{STAGES}}
+    </div>
   </div>
 
   <footer class="page-footer font-small blue pt-4">
@@ -88,11 +100,53 @@ var HtmlTemplate = `
 
   <script>
     new Tablesort(document.getElementsByClassName('aping-table')[0]);
+
+    // Highlight the operations breaching their SLO
+    var sloColumn = $('.aping-table thead th').filter(function () { return $(this).text().trim().toUpperCase() === 'SLO'; }).index();
+    if (sloColumn >= 0) {
+      $('.aping-table tbody tr').each(function () {
+        if ($(this).children().eq(sloColumn).text().indexOf('{{SLO_BREACHED}}') === 0) {
+          $(this).addClass('table-danger');
+        }
+      });
+    }
   </script>
 </body>
 </html>
 `
 
+// The output formats
+const (
+	OutputConsole  = "console"
+	OutputCSV      = "csv"
+	OutputHTML     = "html"
+	OutputMarkdown = "md"
+	OutputJSON     = "json"
+	OutputJUnit    = "junit"
+)
+
+// The output file to write to stdout
+const StdoutOutput = "-"
+
+// The default file extensions by output format
+var OutputExtensions = map[string]string{
+	OutputConsole:  "txt",
+	OutputCSV:      "csv",
+	OutputHTML:     "html",
+	OutputMarkdown: "md",
+	OutputJSON:     "json",
+	OutputJUnit:    "xml",
+}
+
+// The parsed outputs and the spec title to name their files after
+var (
+	outputs   []Output
+	specTitle string
+)
+
+// Matching pattern for characters not allowed in file names
+var regExFileNamePattern = regexp.MustCompile(`[^a-z0-9._-]+`)
+
 // Result table collector
 var (
 	tableWriter       table.Writer
@@ -100,55 +154,304 @@ var (
 		{Name: "Path"},
 		{Name: "URL"},
 		{Name: "Method", WidthMax: 8},
-		{Name: "Avg. ms"},
+		{Name: "Operation"},
+		{Name: "Count"},
+		{Name: "Min ms", Align: text.AlignRight},
+		{Name: "Mean ms", Align: text.AlignRight},
+		{Name: "StdDev ms", Align: text.AlignRight},
+		{Name: "p50 ms", Align: text.AlignRight},
+		{Name: "p90 ms", Align: text.AlignRight},
+		{Name: "p95 ms", Align: text.AlignRight},
+		{Name: "p99 ms", Align: text.AlignRight},
+		{Name: "p99.9 ms", Align: text.AlignRight},
+		{Name: "Max ms", Align: text.AlignRight},
+		{Name: "Status"},
+		{Name: "OK / Errors"},
 		{Name: "Response", WidthMax: 100},
+		{Name: "Corrected p95 ms", Align: text.AlignRight},
+		{Name: "Corrected p99 ms", Align: text.AlignRight},
+		{Name: "Max Delay ms", Align: text.AlignRight},
+		{Name: "Violations", WidthMax: 100},
+		{Name: "SLO", WidthMax: 60},
 	}
 )
 
-// Flush all collected results to the aspired output
-func flush(title string, output *string) {
+// Flush all collected results to the aspired outputs
+func flush(title string) {
 	// Create a table writer to log to
 	tableWriter = table.NewWriter()
 	tableWriter.SetAutoIndex(true)
-	tableWriter.AppendHeader(table.Row{"Path", "URL", "Method", "Avg. ms", "Response"})
+	header := table.Row{"Path", "URL", "Method", "Operation", "Count", "Min ms", "Mean ms", "StdDev ms", "p50 ms", "p90 ms", "p95 ms", "p99 ms", "p99.9 ms", "Max ms", "Status", "OK / Errors", "Response"}
+	if hasRates() {
+		header = append(header, "Corrected p95 ms", "Corrected p99 ms", "Max Delay ms")
+	}
+	if *validateFlag {
+		header = append(header, "Violations")
+	}
+	if len(slos) > 0 {
+		header = append(header, "SLO")
+		// Highlight the operations breaching their SLO
+		tableWriter.SetRowPainter(func(row table.Row) text.Colors {
+			if slo, ok := row[len(row)-1].(string); ok && strings.HasPrefix(slo, SLOBreached) {
+				return text.Colors{text.FgHiRed}
+			}
+			return nil
+		})
+	}
+	tableWriter.AppendHeader(header)
 	tableWriter.SetColumnConfigs(tableColumnConfig)
 	tableWriter.SetHTMLCSSClass("sort table table-striped table-hover table-responsive aping-table")
+	// Record the seed to replay the run
+	tableWriter.SetCaption(fmt.Sprintf("Seed: %d", seed))
+	runTime := time.Now()
 
-	// Flush the pongs
-	for _, result := range Results {
-		tableWriter.AppendRow(table.Row{
+	// Summarize all latencies, unresolved links and SLO breaches
+	for key, result := range Results {
+		result.Latency = result.Histogram.getLatency()
+		if result.CorrectedHistogram != nil {
+			corrected, delay := result.CorrectedHistogram.getLatency(), result.DelayHistogram.getLatency()
+			result.Corrected, result.Delay = &corrected, &delay
+		}
+		if result.OperationId != "" {
+			result.UnresolvedLinks = UnresolvedLinks[result.OperationId]
+		}
+		result.SLO = slos[key]
+		result.SLOBreaches = getSLOBreaches(result.SLO, result)
+		Results[key] = result
+	}
+	for name, result := range TokenResults {
+		result.Latency = result.Histogram.getLatency()
+		TokenResults[name] = result
+	}
+	for key, result := range WarmupResults {
+		result.Latency = result.Histogram.getLatency()
+		WarmupResults[key] = result
+	}
+	summarizeStages()
+
+	// Flush the pongs, one row per operation, followed by the token endpoints
+	rows := make([]Pongs, 0, len(Results)+len(TokenResults))
+	for _, key := range getSortedResultKeys() {
+		rows = append(rows, Results[key])
+	}
+	for _, name := range getSortedTokenResultNames() {
+		rows = append(rows, TokenResults[name])
+	}
+	for _, result := range rows {
+		row := table.Row{
 			result.Path,
 			strings.Join(result.Urls, "\r\n"),
 			result.Method,
-			result.Time / int64(*loopFlag),
+			result.OperationId,
+			result.Latency.Count,
+			formatMS(result.Latency.Min),
+			formatMS(result.Latency.Mean),
+			formatMS(result.Latency.StdDev),
+			formatMS(result.Latency.P50),
+			formatMS(result.Latency.P90),
+			formatMS(result.Latency.P95),
+			formatMS(result.Latency.P99),
+			formatMS(result.Latency.P999),
+			formatMS(result.Latency.Max),
+			formatOutcomes(result),
+			fmt.Sprintf("%d / %d", result.Successes, result.Errors),
 			strings.Join(result.Responses, "\r\n"),
-		})
+		}
+		if hasRates() {
+			if result.Corrected != nil {
+				row = append(row, formatMS(result.Corrected.P95), formatMS(result.Corrected.P99), formatMS(result.Delay.Max))
+			} else {
+				row = append(row, "-", "-", "-")
+			}
+		}
+		if *validateFlag {
+			row = append(row, formatViolations(result))
+		}
+		if len(slos) > 0 {
+			row = append(row, formatSLO(result))
+		}
+		tableWriter.AppendRow(row)
+	}
+
+	// Write every output to its file or stdout
+	for _, output := range outputs {
+		data := renderOutput(output.Format, title, runTime)
+		switch {
+		case output.Format == OutputConsole && output.File == "":
+			log.Println("\n" + string(data))
+		case output.File == StdoutOutput:
+			fmt.Println(string(data))
+		default:
+			file := getOutputFile(output.File, runTime)
+			if dir := filepath.Dir(file); dir != "." {
+				checkFatalError(os.MkdirAll(dir, 0755))
+			}
+			checkFatalError(ioutil.WriteFile(file, data, 0644))
+			log.Println(fmt.Sprintf("[aPing] Written the %s output to %s", output.Format, file))
+		}
+	}
+}
+
+// Render the results in the given format
+func renderOutput(format string, title string, runTime time.Time) []byte {
+	date := runTime.Format("2006-01-02 15:04:05")
+	switch format {
+	case OutputCSV:
+		return []byte(tableWriter.RenderCSV())
+	case OutputHTML:
+		html := strings.Replace(HtmlTemplate, "{{TITLE}}", title, 1)
+		html = strings.Replace(html, "{{DATE}}", date, 1)
+		html = strings.Replace(html, "{{SEED}}", strconv.FormatInt(seed, 10), 1)
+		html = strings.Replace(html, "{{SLO_BREACHED}}", SLOBreached, 1)
+		html = strings.Replace(html, "{{TABLE}}", tableWriter.RenderHTML(), 1)
+		// The stages follow the operations
+		stagesHtml := ""
+		if stages != nil {
+			stagesHtml = renderStages(OutputHTML)
+		}
+		html = strings.Replace(html, "{{STAGES}}", stagesHtml, 1)
+		return []byte(html)
+	case OutputMarkdown:
+		if stages != nil {
+			return []byte(tableWriter.RenderMarkdown() + "\n\n" + renderStages(OutputMarkdown))
+		}
+		return []byte(tableWriter.RenderMarkdown())
+	case OutputJSON:
+		data, err := json.MarshalIndent(Report{
+			Title:   title,
+			Date:    date,
+			Seed:    seed,
+			Results: Results,
+			Tokens:  TokenResults,
+			Stages:  StageResults,
+			Warmup:  WarmupResults,
+		}, "", " ")
+		checkFatalError(err)
+		return data
+	case OutputJUnit:
+		data, err := renderJUnit(title, runTime.Format("2006-01-02T15:04:05"))
+		checkFatalError(err)
+		return data
+	default:
+		// The stages follow the operations
+		if stages != nil {
+			return []byte(tableWriter.Render() + "\n" + renderStages(OutputConsole))
+		}
+		return []byte(tableWriter.Render())
+	}
+}
+
+// Parse the comma separated output formats, each with an optional file, e.g. "console,json=reports/run.json"
+func parseOutputs(swagger *openapi3.Swagger) {
+	outputs = make([]Output, 0)
+	for _, entry := range strings.Split(*outputFlag, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		output := Output{Format: strings.ToLower(entry)}
+		if index := strings.Index(entry, "="); index >= 0 {
+			output.Format = strings.ToLower(strings.TrimSpace(entry[:index]))
+			output.File = strings.TrimSpace(entry[index+1:])
+		}
+		extension, ok := OutputExtensions[output.Format]
+		if !ok {
+			checkFatalError(fmt.Errorf("[aPing] Unknown output format '%s'", output.Format))
+		}
+		// The console is logged, the others are written to the working directory by default
+		if output.File == "" && output.Format != OutputConsole {
+			output.File = "aping." + extension
+		}
+		// Keep stdout clean of the progress, e.g. to pipe the output
+		if output.File == StdoutOutput {
+			progressWriter.SetOutputWriter(os.Stderr)
+		}
+		outputs = append(outputs, output)
+	}
+	if len(outputs) == 0 {
+		outputs = append(outputs, Output{Format: OutputConsole})
+	}
+
+	// Name the files after the spec, or else the input
+	specTitle = *inputFlag
+	if swagger.Info != nil && swagger.Info.Title != "" {
+		specTitle = swagger.Info.Title
 	}
+	specTitle = strings.Trim(regExFileNamePattern.ReplaceAllString(strings.ToLower(specTitle), "-"), "-.")
+	if specTitle == "" {
+		specTitle = "aping"
+	}
+}
+
+// Resolve the placeholders of an output file name, e.g. "reports/{{TITLE}}-{{TIMESTAMP}}.json"
+func getOutputFile(file string, timestamp time.Time) string {
+	file = strings.ReplaceAll(file, "{{TIMESTAMP}}", timestamp.Format("20060102-150405"))
+	file = strings.ReplaceAll(file, "{{TITLE}}", specTitle)
+	file = strings.ReplaceAll(file, "{{SEED}}", strconv.FormatInt(seed, 10))
+	return file
+}
+
+// Get all result keys sorted by path and method for a stable output
+func getSortedResultKeys() []string {
+	keys := make([]string, 0, len(Results))
+	for key := range Results {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if Results[keys[i]].Path != Results[keys[j]].Path {
+			return Results[keys[i]].Path < Results[keys[j]].Path
+		}
+		return Results[keys[i]].Method < Results[keys[j]].Method
+	})
+	return keys
+}
+
+// Get all token result scheme names sorted
+func getSortedTokenResultNames() []string {
+	names := make([]string, 0, len(TokenResults))
+	for name := range TokenResults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Format the status code and error category distribution, e.g. "200: 3, 500: 1, timeout: 2"
+func formatOutcomes(result Pongs) string {
+	statusCodes := make([]int, 0, len(result.StatusCodes))
+	for statusCode := range result.StatusCodes {
+		statusCodes = append(statusCodes, statusCode)
+	}
+	sort.Ints(statusCodes)
+	errorCategories := make([]string, 0, len(result.ErrorCategories))
+	for errorCategory := range result.ErrorCategories {
+		errorCategories = append(errorCategories, errorCategory)
+	}
+	sort.Strings(errorCategories)
+
+	outcomes := make([]string, 0, len(statusCodes)+len(errorCategories))
+	for _, statusCode := range statusCodes {
+		outcomes = append(outcomes, fmt.Sprintf("%d: %d", statusCode, result.StatusCodes[statusCode]))
+	}
+	for _, errorCategory := range errorCategories {
+		outcomes = append(outcomes, fmt.Sprintf("%s: %d", errorCategory, result.ErrorCategories[errorCategory]))
+	}
+	if unresolved := countUnresolvedLinks(result); unresolved > 0 {
+		outcomes = append(outcomes, fmt.Sprintf("unresolved links: %d", unresolved))
+	}
+	return strings.Join(outcomes, ", ")
+}
+
+// Format milliseconds with sub-millisecond resolution
+func formatMS(value float64) string {
+	return fmt.Sprintf("%.3f", value)
+}
 
-	// If an output file is given, write to it
-	if output != nil && *output != "" {
-		switch strings.ToLower(*output) {
-		case "console":
-			log.Println("\n" + tableWriter.Render())
-		case "csv":
-			err := ioutil.WriteFile("aping.csv", []byte(tableWriter.RenderCSV()), 0644)
-			checkFatalError(err)
-		case "html":
-			html := strings.Replace(HtmlTemplate, "{{TITLE}}", title, 1)
-			html = strings.Replace(html, "{{DATE}}", time.Now().Format("2006-01-02 15:04:05"), 1)
-			html = strings.Replace(html, "{{TABLE}}", tableWriter.RenderHTML(), 1)
-			err := ioutil.WriteFile("aping.html", []byte(html), 0644)
-			checkFatalError(err)
-		case "md":
-			err := ioutil.WriteFile("aping.md", []byte(tableWriter.RenderMarkdown()), 0644)
-			checkFatalError(err)
-		case "json":
-			file, _ := json.MarshalIndent(Results, "", " ")
-			err := ioutil.WriteFile("aping.json", file, 0644)
-			checkFatalError(err)
-		}
-	} else {
-		// Otherwise just print the output
-		log.Println("\n" + tableWriter.Render())
+// Count all unresolved link expressions of an operation
+func countUnresolvedLinks(result Pongs) int {
+	count := 0
+	for _, unresolved := range result.UnresolvedLinks {
+		count += unresolved
 	}
+	return count
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOutputs(t *testing.T) {
+	defer func(previousFlag string, previousInput string) {
+		*outputFlag, *inputFlag = previousFlag, previousInput
+	}(*outputFlag, *inputFlag)
+
+	tests := []struct {
+		name     string
+		flag     string
+		title    string
+		expected []Output
+		file     string
+	}{
+		{"default", "", "Pets", []Output{{Format: OutputConsole}}, "pets"},
+		{"format", "json", "Pets", []Output{{Format: OutputJSON, File: "aping.json"}}, "pets"},
+		{"format case", " HTML ", "Pets", []Output{{Format: OutputHTML, File: "aping.html"}}, "pets"},
+		{"format=file", "junit=reports/{{TITLE}}.xml", "Pets", []Output{{Format: OutputJUnit, File: "reports/{{TITLE}}.xml"}}, "pets"},
+		{"stdout", "json=-", "Pets", []Output{{Format: OutputJSON, File: StdoutOutput}}, "pets"},
+		{"several", "console, csv=out.csv,md", "Pets", []Output{{Format: OutputConsole}, {Format: OutputCSV, File: "out.csv"}, {Format: OutputMarkdown, File: "aping.md"}}, "pets"},
+		{"title file name", "console", "My Pet Store API (v2)", []Output{{Format: OutputConsole}}, "my-pet-store-api-v2"},
+		{"input without title", "console", "", []Output{{Format: OutputConsole}}, "specs-pets.yaml"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			*outputFlag, *inputFlag = test.flag, "specs/Pets.yaml"
+			parseOutputs(&openapi3.Swagger{Info: &openapi3.Info{Title: test.title}})
+			if !reflect.DeepEqual(outputs, test.expected) {
+				t.Errorf("%v, expected %v", outputs, test.expected)
+			}
+			if specTitle != test.file {
+				t.Errorf("title '%s', expected '%s'", specTitle, test.file)
+			}
+		})
+	}
+}
+
+func TestGetOutputFile(t *testing.T) {
+	defer func(previousTitle string, previousSeed int64) {
+		specTitle, seed = previousTitle, previousSeed
+	}(specTitle, seed)
+
+	specTitle, seed = "pets", 42
+	timestamp := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"aping.json", "aping.json"},
+		{"{{TITLE}}.json", "pets.json"},
+		{"reports/{{TITLE}}-{{TIMESTAMP}}.xml", "reports/pets-20200304-050607.xml"},
+		{"{{TITLE}}-{{SEED}}-{{SEED}}.md", "pets-42-42.md"},
+		{StdoutOutput, StdoutOutput},
+	}
+	for _, test := range tests {
+		if actual := getOutputFile(test.file, timestamp); actual != test.expected {
+			t.Errorf("'%s' is '%s', expected '%s'", test.file, actual, test.expected)
+		}
+	}
+}
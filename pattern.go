@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math/rand"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// The maximum repetitions for unbounded quantifiers, e.g. "*" or "+"
+const patternMaxRepeat = 8
+
+// Generate a random string matching the given regular expression pattern
+func generatePattern(random *rand.Rand, pattern string) (string, bool) {
+	regExp, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	var builder strings.Builder
+	if !generatePatternNode(random, &builder, regExp.Simplify()) {
+		return "", false
+	}
+	return builder.String(), true
+}
+
+// Write a random match of a parsed pattern node
+func generatePatternNode(random *rand.Rand, builder *strings.Builder, regExp *syntax.Regexp) bool {
+	switch regExp.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	case syntax.OpLiteral:
+		for _, r := range regExp.Rune {
+			if regExp.Flags&syntax.FoldCase != 0 && random.Intn(2) == 1 {
+				r = unicode.SimpleFold(r)
+			}
+			builder.WriteRune(r)
+		}
+		return true
+	case syntax.OpCharClass:
+		return generatePatternCharClass(random, builder, regExp.Rune)
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		builder.WriteByte(RandomStringCharset[random.Intn(len(RandomStringCharset))])
+		return true
+	case syntax.OpCapture:
+		return generatePatternNode(random, builder, regExp.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range regExp.Sub {
+			if !generatePatternNode(random, builder, sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		return generatePatternNode(random, builder, regExp.Sub[random.Intn(len(regExp.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := getPatternRepeat(regExp)
+		count := min + random.Intn(max-min+1)
+		for i := 0; i < count; i++ {
+			if !generatePatternNode(random, builder, regExp.Sub[0]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Get the bounded minimum and maximum repetitions of a quantifier
+func getPatternRepeat(regExp *syntax.Regexp) (int, int) {
+	switch regExp.Op {
+	case syntax.OpStar:
+		return 0, patternMaxRepeat
+	case syntax.OpPlus:
+		return 1, patternMaxRepeat
+	case syntax.OpQuest:
+		return 0, 1
+	}
+	max := regExp.Max
+	if max < 0 || max > regExp.Min+patternMaxRepeat {
+		max = regExp.Min + patternMaxRepeat
+	}
+	return regExp.Min, max
+}
+
+// Write a random rune of a character class, preferring printable ASCII ranges
+func generatePatternCharClass(random *rand.Rand, builder *strings.Builder, ranges []rune) bool {
+	if len(ranges) == 0 {
+		return false
+	}
+	// Negated classes, e.g. [^a], cover (almost) everything, pick printable ranges first
+	printable := make([]rune, 0, len(ranges))
+	for i := 0; i+1 < len(ranges); i += 2 {
+		low, high := ranges[i], ranges[i+1]
+		if low < ' ' {
+			low = ' '
+		}
+		if high > '~' {
+			high = '~'
+		}
+		if low <= high {
+			printable = append(printable, low, high)
+		}
+	}
+	if len(printable) > 0 {
+		ranges = printable
+	}
+
+	pair := random.Intn(len(ranges)/2) * 2
+	low, high := ranges[pair], ranges[pair+1]
+	builder.WriteRune(low + rune(random.Intn(int(high-low)+1)))
+	return true
+}
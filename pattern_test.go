@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"math/rand"
+	"regexp"
+	"testing"
+)
+
+func TestGeneratePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"literal", `^abc$`},
+		{"char class", `^[a-z]{3}$`},
+		{"ranges", `^[A-Z][a-z0-9_-]{2,5}$`},
+		{"negated class", `^[^0-9]{4}$`},
+		{"digits", `^\d{3}-\d{4}$`},
+		{"alternation", `^(cat|dog|bird)s?$`},
+		{"unbounded", `^x+y*z?$`},
+		{"any char", `^.{2}\.json$`},
+		{"case fold", `^(?i)hello$`},
+		{"unanchored", `[0-9a-f]{8}`},
+		{"uuid", `^[0-9a-f]{8}-[0-9a-f]{4}-[1-5][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regExp := regexp.MustCompile(test.pattern)
+			random := rand.New(rand.NewSource(1))
+			for i := 0; i < 50; i++ {
+				value, ok := generatePattern(random, test.pattern)
+				if !ok {
+					t.Fatalf("cannot generate '%s'", test.pattern)
+				}
+				if !regExp.MatchString(value) {
+					t.Fatalf("'%s' does not match '%s'", value, test.pattern)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratePatternInvalid(t *testing.T) {
+	if _, ok := generatePattern(rand.New(rand.NewSource(1)), `^[a-z`); ok {
+		t.Errorf("generated a value of an invalid pattern")
+	}
+}
+
+func TestGenerateStringLength(t *testing.T) {
+	maxLength := func(value uint64) *uint64 {
+		return &value
+	}
+	tests := []struct {
+		name   string
+		schema *openapi3.Schema
+		ok     bool
+	}{
+		{"pattern within", &openapi3.Schema{Pattern: `^[a-z]{2,6}$`, MinLength: 3, MaxLength: maxLength(4)}, true},
+		{"pattern too short", &openapi3.Schema{Pattern: `^[a-z]{1,3}$`, MinLength: 10}, false},
+		{"pattern too long", &openapi3.Schema{Pattern: `^[a-z]{5}$`, MaxLength: maxLength(4)}, false},
+		{"length", &openapi3.Schema{MinLength: 2, MaxLength: maxLength(5)}, true},
+		{"length contradicting", &openapi3.Schema{MinLength: 6, MaxLength: maxLength(5)}, false},
+	}
+	random := rand.New(rand.NewSource(1))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, ok := generateString(random, test.schema)
+			if ok != test.ok {
+				t.Fatalf("generated %v (%t), expected %t", value, ok, test.ok)
+			}
+			if ok && !isValidLength(test.schema, value.(string)) {
+				t.Errorf("'%s' is out of the length bounds", value)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jedib0t/go-pretty/progress"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// The own request rates per second by operation key, if any
+var operationRates = make(map[string]float64)
+
+// A fixed arrival timeline of a rate, independent of the response times
+type Timeline struct {
+	interval time.Duration
+	next     time.Time
+	// The ramped rate of the stages, if any
+	rate func(time.Time) float64
+}
+
+// Create a timeline of the rate per second, starting at the given time
+func newTimeline(rate float64, start time.Time) *Timeline {
+	return &Timeline{interval: time.Duration(float64(time.Second) / rate), next: start}
+}
+
+// Wait for the next arrival on the timeline. Returns its intended start time, which may be in the past already
+func (timeline *Timeline) wait() time.Time {
+	intended := timeline.next
+	if timeline.rate != nil {
+		intended = timeline.ramp()
+		timeline.next = intended
+	} else {
+		timeline.next = timeline.next.Add(timeline.interval)
+	}
+	if delay := time.Until(intended); delay > 0 {
+		time.Sleep(delay)
+	}
+	return intended
+}
+
+// Get the next arrival of the ramped rate, once the rate integrated from the last one adds up to a request.
+// Integrates in steps of the stage poll interval, waiting for each step to start to check the budget
+func (timeline *Timeline) ramp() time.Time {
+	at, arrivals := timeline.next, 0.0
+	for {
+		rate := timeline.rate(at.Add(stagePollInterval / 2))
+		step := rate * stagePollInterval.Seconds()
+		if arrivals+step >= 1 {
+			return at.Add(time.Duration((1 - arrivals) / rate * float64(time.Second)))
+		}
+		arrivals += step
+		at = at.Add(stagePollInterval)
+		// Nothing to start at the moment, check again later (until the budget ends)
+		if budget.exhausted() {
+			return at
+		}
+		if delay := time.Until(at); delay > 0 {
+			time.Sleep(delay)
+		}
+	}
+}
+
+// Parse the global rate and any own rates by operationId or operation key, e.g. '{"getOrder": 5, "GET /items": 20}'
+func parseRates(operations []Operation) {
+	if *rateFlag < 0 {
+		checkFatalError(fmt.Errorf("[aPing] The rate cannot be negative"))
+	}
+	if ratesFlag != nil && *ratesFlag != "" {
+		rates := make(map[string]float64)
+		checkFatalError(json.Unmarshal([]byte(*ratesFlag), &rates))
+		for name, rate := range rates {
+			if rate <= 0 {
+				checkFatalError(fmt.Errorf("[aPing] The rate of '%s' must be positive", name))
+			}
+			found := false
+			for _, operation := range operations {
+				key := getOperationKey(operation.Method, operation.Path)
+				if name == key || (operation.Operation.OperationID != "" && name == operation.Operation.OperationID) {
+					operationRates[key] = rate
+					found = true
+				}
+			}
+			if !found {
+				checkFatalError(fmt.Errorf("[aPing] The rate of '%s' matches no operation", name))
+			}
+		}
+	}
+	if hasRates() && scenario != nil {
+		checkFatalError(fmt.Errorf("[aPing] A rate cannot be combined with a scenario"))
+	}
+}
+
+// Check if any pings run on an arrival timeline
+func hasRates() bool {
+	return *rateFlag > 0 || len(operationRates) > 0
+}
+
+// Ping all operations on their arrival timelines until the budget is exhausted, the own rates of operations separate from the global one.
+// Pings wait for a free worker, which is measured as their delay
+func loopRate(pings int, levels [][]*Operation, budget *Budget, progressTracker *progress.Tracker) {
+	// Prepare the channels
+	var waitGroup sync.WaitGroup
+	jobs := make(chan *Ping, pings)
+
+	// Init some workers
+	var workers sync.WaitGroup
+	for worker := 0; worker < *workerFlag; worker++ {
+		workers.Add(1)
+		go func(session *Session) {
+			defer workers.Done()
+			ping(session, jobs, &waitGroup, progressTracker)
+		}(sessions[worker])
+	}
+
+	// The variables are shared by all timelines, consumers take the latest values of their producers
+	variables := newVariables()
+	start := time.Now()
+	var producers sync.WaitGroup
+	produce := func(operations []*Operation, timeline *Timeline, mixed bool) {
+		producers.Add(1)
+		go func(random *rand.Rand) {
+			defer producers.Done()
+			if mixed {
+				scheduleMix(newMix(operations), random, timeline, budget, variables, jobs, &waitGroup)
+			} else {
+				scheduleOperations(operations, random, timeline, budget, variables, jobs, &waitGroup)
+			}
+		}(nextRand())
+	}
+
+	// Operations without an own rate share the global timeline, producers of links first
+	shared := make([]*Operation, 0)
+	for _, level := range levels {
+		for _, operation := range level {
+			if rate, ok := operationRates[getOperationKey(operation.Method, operation.Path)]; ok {
+				produce([]*Operation{operation}, newTimeline(rate, start), false)
+			} else if *rateFlag > 0 {
+				shared = append(shared, operation)
+			}
+		}
+	}
+	if len(shared) > 0 {
+		timeline := newTimeline(*rateFlag, start)
+		if hasStageRate() {
+			timeline.rate = getStageValue
+		}
+		// The traffic mix picks the operations of the global timeline by weight
+		produce(shared, timeline, hasWeights(budget))
+	}
+
+	// Release the workers
+	producers.Wait()
+	waitGroup.Wait()
+	close(jobs)
+	workers.Wait()
+}
+
+// Queue the operations round after round at the arrivals of the timeline, until the budget is exhausted
+func scheduleOperations(operations []*Operation, random *rand.Rand, timeline *Timeline, budget *Budget, variables *Variables, jobs chan<- *Ping, waitGroup *sync.WaitGroup) {
+	for {
+		queued := 0
+		for _, operation := range operations {
+			// Skip routes with request bodies we cannot generate
+			contentType, body, parsed := parseBody(random, operation.Operation)
+			if !parsed {
+				continue
+			}
+			// Skip routes we cannot parse (yet)
+			request, parsed := parseRequest(random, operation.Path, operation.PathItem, operation.Operation, variables)
+			if !parsed {
+				continue
+			}
+			// Arrivals within the duration are sent, even if they are late
+			intended := timeline.wait()
+			if !budget.takeAt(intended) {
+				return
+			}
+			contentType, body = getLinkedBody(operation, variables, contentType, body)
+			ping := newPing(operation, request, contentType, body)
+			ping.intended = intended
+			if hasLinks(operation) {
+				ping.Capture = true
+				ping.variables = variables
+			}
+			// Fire
+			waitGroup.Add(1)
+			jobs <- ping
+			queued++
+		}
+		// Stop if nothing could be pinged (anymore)
+		if queued == 0 {
+			return
+		}
+	}
+}
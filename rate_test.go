@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTimelineWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		expected time.Duration
+	}{
+		{"ten per second", 10, 100 * time.Millisecond},
+		{"one per second", 1, time.Second},
+		{"fraction", 0.5, 2 * time.Second},
+		{"fast", 4000, 250 * time.Microsecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrivals in the past are returned at once
+			start := time.Now().Add(-time.Hour)
+			timeline := newTimeline(test.rate, start)
+			for i := 0; i < 5; i++ {
+				if intended := timeline.wait(); !intended.Equal(start.Add(time.Duration(i) * test.expected)) {
+					t.Errorf("arrival %d at %s, expected %s", i, intended.Sub(start), time.Duration(i)*test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestTimelineWaitSleeps(t *testing.T) {
+	start := time.Now()
+	timeline := newTimeline(100, start)
+	for i := 0; i < 5; i++ {
+		timeline.wait()
+	}
+	// The fifth arrival is 40ms after the first one
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("waited %s for 5 arrivals at 100/s, expected at least 40ms", elapsed)
+	}
+}
+
+func TestTimelineRamp(t *testing.T) {
+	defer func(previous *Budget) {
+		budget = previous
+	}(budget)
+	budget = &Budget{}
+
+	tests := []struct {
+		name     string
+		rate     func(elapsed float64) float64
+		expected int
+	}{
+		{"constant", func(elapsed float64) float64 { return 10 }, 10},
+		{"ramp up", func(elapsed float64) float64 { return 20 * math.Min(elapsed, 1) }, 10},
+		{"ramp down", func(elapsed float64) float64 { return 20 - 20*math.Min(elapsed, 0.99) }, 10},
+		{"from one", func(elapsed float64) float64 { return 1 + 19*math.Min(elapsed, 1) }, 10},
+		{"idle", func(elapsed float64) float64 { return 10 * math.Floor(elapsed) }, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrivals in the past are returned at once, the second after the start counts
+			start := time.Now().Add(-time.Hour)
+			end := start.Add(time.Second)
+			timeline := newTimeline(1, start)
+			timeline.rate = func(at time.Time) float64 {
+				return test.rate(at.Sub(start).Seconds())
+			}
+			arrivals := 0
+			for intended := timeline.wait(); intended.Before(end); intended = timeline.wait() {
+				arrivals++
+			}
+			if math.Abs(float64(arrivals-test.expected)) > 1 {
+				t.Errorf("%d arrivals in the first second, expected %d", arrivals, test.expected)
+			}
+		})
+	}
+}
+
+func TestRecordPongCorrected(t *testing.T) {
+	intended := time.Now()
+	tests := []struct {
+		name      string
+		delays    []time.Duration
+		times     []time.Duration
+		corrected float64
+		delay     float64
+	}{
+		{"on time", []time.Duration{0, 0}, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}, 10, 0},
+		{"delayed", []time.Duration{90 * time.Millisecond, 90 * time.Millisecond}, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}, 100, 90},
+		{"mixed", []time.Duration{0, 40 * time.Millisecond, 190 * time.Millisecond}, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond}, 200, 190},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Pongs{}
+			for i := range test.delays {
+				pong := &Pong{Ping: Ping{Method: "GET", Path: "/pets", intended: intended}, StatusCode: 200, Delay: test.delays[i], Time: test.times[i]}
+				result = recordPong(result, pong)
+			}
+			if result.CorrectedHistogram == nil || result.DelayHistogram == nil {
+				t.Fatal("the corrected latencies are not recorded")
+			}
+			latency := result.Histogram.getLatency()
+			corrected := result.CorrectedHistogram.getLatency()
+			delay := result.DelayHistogram.getLatency()
+			if math.Abs(latency.Max-10) > 10*histogramTestError {
+				t.Errorf("max latency %g ms, expected the response time of 10 ms", latency.Max)
+			}
+			if math.Abs(corrected.Max-test.corrected) > test.corrected*histogramTestError {
+				t.Errorf("max corrected %g ms, expected %g ms", corrected.Max, test.corrected)
+			}
+			if math.Abs(delay.Max-test.delay) > test.delay*histogramTestError {
+				t.Errorf("max delay %g ms, expected %g ms", delay.Max, test.delay)
+			}
+		})
+	}
+
+	// Pings without a timeline are not corrected
+	result := recordPong(Pongs{}, &Pong{Ping: Ping{Method: "GET", Path: "/pets"}, StatusCode: 200, Time: time.Millisecond})
+	if result.CorrectedHistogram != nil {
+		t.Errorf("a ping without a timeline is corrected")
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Header parameters the spec ignores, as they are defined elsewhere
+var ignoredHeaderParameters = []string{"accept", "content-type", "authorization"}
+
+// Create a "pingable" request with all parameters in place, i.e. url with path and query parameters, headers and cookies,
+// authenticated by the security requirements
+func parseRequest(random *rand.Rand, path string, pathItem *openapi3.PathItem, operation *openapi3.Operation, variables *Variables) (Request, bool) {
+	request, query, parsed := parseParameters(random, path, pathItem, operation, variables)
+	if !parsed {
+		return request, false
+	}
+
+	// Authenticate by the security requirements
+	query = append(query, applySecurity(&request, operation)...)
+
+	if len(query) > 0 {
+		request.Url += "?" + strings.Join(query, "&")
+	}
+	return request, true
+}
+
+// Put all parameters in place, i.e. the url path, headers and cookies. Returns the query pairs to add.
+// Any variables extracted from previous responses take precedence over fixtures and generated values
+func parseParameters(random *rand.Rand, path string, pathItem *openapi3.PathItem, operation *openapi3.Operation, variables *Variables) (Request, []string, bool) {
+	request := Request{
+		Headers:    make(map[string]string),
+		Cookies:    make(map[string]string),
+		Parameters: make(map[string]interface{}),
+	}
+
+	// Filter paths, if set
+	if isFilteredPath(path) {
+		return request, nil, false
+	}
+
+	// Parameters are replaced in the path, fixtures are matched against the template
+	template := path
+	query := make([]string, 0)
+	for _, parameter := range getParameters(pathItem, operation) {
+		in := parameter.In
+		if in == openapi3.ParameterInHeader {
+			if _, isIgnored := contains(ignoredHeaderParameters, strings.ToLower(parameter.Name)); isIgnored {
+				continue
+			}
+		}
+
+		// Variables and fixture values first, even for optional parameters
+		value, ok := variables.get(operation.OperationID, parameter.Name)
+		if !ok {
+			value, ok = getFixtureValue(random, operation.OperationID, template, parameter.Name)
+		}
+		if !ok {
+			// Required or path parameter, which is always required
+			if !parameter.Required && in != openapi3.ParameterInPath {
+				continue
+			}
+			// Cannot parse at least one parameter => don't ping!
+			if value, ok = generateParameterValue(random, parameter); !ok {
+				return request, nil, false
+			}
+		}
+		serializationMethod, err := parameter.SerializationMethod()
+		if err != nil {
+			return request, nil, false
+		}
+		request.Parameters[parameter.Name] = value
+
+		switch in {
+		case openapi3.ParameterInPath:
+			path = strings.Replace(path, "{"+parameter.Name+"}", serializePathParameter(parameter, serializationMethod, value), 1)
+		case openapi3.ParameterInQuery:
+			query = append(query, serializeQueryParameter(parameter, serializationMethod, value)...)
+		case openapi3.ParameterInHeader:
+			request.Headers[parameter.Name] = serializeList(value, serializationMethod.Explode, ",", noEscape)
+		case openapi3.ParameterInCookie:
+			request.Cookies[parameter.Name] = serializeList(value, false, ",", url.QueryEscape)
+		}
+	}
+
+	request.Url = basePath + path
+	return request, query, true
+}
+
+// Merge the path item parameters with the operation parameters, the latter override by name and location
+func getParameters(pathItem *openapi3.PathItem, operation *openapi3.Operation) []*openapi3.Parameter {
+	parameters := make([]*openapi3.Parameter, 0, len(operation.Parameters))
+	for _, parameterRef := range operation.Parameters {
+		if parameterRef.Value != nil {
+			parameters = append(parameters, parameterRef.Value)
+		}
+	}
+	if pathItem == nil {
+		return parameters
+	}
+	for _, parameterRef := range pathItem.Parameters {
+		if parameterRef.Value == nil || operation.Parameters.GetByInAndName(parameterRef.Value.In, parameterRef.Value.Name) != nil {
+			continue
+		}
+		parameters = append(parameters, parameterRef.Value)
+	}
+	return parameters
+}
+
+// Generate a parameter value from its examples or schema. Parameters with content are serialized as JSON
+func generateParameterValue(random *rand.Rand, parameter *openapi3.Parameter) (interface{}, bool) {
+	if parameter.Example != nil {
+		return parameter.Example, true
+	}
+	if example, ok := getFirstExample(parameter.Examples); ok {
+		return example, true
+	}
+	if parameter.Schema != nil {
+		return generateValue(random, parameter.Schema, 0)
+	}
+
+	for _, contentType := range getSortedContentTypes(parameter.Content) {
+		if !isJSONContentType(contentType) {
+			continue
+		}
+		value, ok := getMediaTypeExample(parameter.Content[contentType])
+		if !ok {
+			value, ok = generateValue(random, parameter.Content[contentType].Schema, 0)
+		}
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		return string(data), true
+	}
+	return nil, false
+}
+
+// Serialize a path parameter in simple, label or matrix style
+func serializePathParameter(parameter *openapi3.Parameter, serializationMethod *openapi3.SerializationMethod, value interface{}) string {
+	explode := serializationMethod.Explode
+	switch serializationMethod.Style {
+	case openapi3.SerializationLabel:
+		separator := ","
+		if explode {
+			separator = "."
+		}
+		return "." + serializeList(value, explode, separator, url.PathEscape)
+	case openapi3.SerializationMatrix:
+		name := url.PathEscape(parameter.Name)
+		switch v := value.(type) {
+		case []interface{}:
+			if explode {
+				items := make([]string, len(v))
+				for i, item := range v {
+					items[i] = ";" + name + "=" + url.PathEscape(formatValue(item))
+				}
+				return strings.Join(items, "")
+			}
+		case map[string]interface{}:
+			if explode {
+				return ";" + serializeList(value, true, ";", url.PathEscape)
+			}
+		}
+		return ";" + name + "=" + serializeList(value, false, ",", url.PathEscape)
+	}
+	return serializeList(value, explode, ",", url.PathEscape)
+}
+
+// Serialize a query parameter in form, space delimited, pipe delimited or deep object style to "key=value" pairs
+func serializeQueryParameter(parameter *openapi3.Parameter, serializationMethod *openapi3.SerializationMethod, value interface{}) []string {
+	escape := url.QueryEscape
+	if parameter.AllowReserved {
+		escape = escapeUnreserved
+	}
+	name := url.QueryEscape(parameter.Name)
+	explode := serializationMethod.Explode
+
+	switch serializationMethod.Style {
+	case openapi3.SerializationSpaceDelimited:
+		return []string{name + "=" + serializeList(value, false, "%20", escape)}
+	case openapi3.SerializationPipeDelimited:
+		return []string{name + "=" + serializeList(value, false, "|", escape)}
+	case openapi3.SerializationDeepObject:
+		if object, isObject := value.(map[string]interface{}); isObject {
+			pairs := make([]string, 0, len(object))
+			for _, key := range getSortedKeys(object) {
+				pairs = append(pairs, name+"%5B"+url.QueryEscape(key)+"%5D="+escape(formatValue(object[key])))
+			}
+			return pairs
+		}
+	}
+
+	// Form style
+	if explode {
+		switch v := value.(type) {
+		case []interface{}:
+			pairs := make([]string, len(v))
+			for i, item := range v {
+				pairs[i] = name + "=" + escape(formatValue(item))
+			}
+			return pairs
+		case map[string]interface{}:
+			pairs := make([]string, 0, len(v))
+			for _, key := range getSortedKeys(v) {
+				pairs = append(pairs, url.QueryEscape(key)+"="+escape(formatValue(v[key])))
+			}
+			return pairs
+		}
+	}
+	return []string{name + "=" + serializeList(value, false, ",", escape)}
+}
+
+// Serialize a primitive, array or object value, joined by the separator.
+// Exploded objects are joined as "k=v", otherwise keys and values alternate
+func serializeList(value interface{}, explode bool, separator string, escape func(string) string) string {
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = escape(formatValue(item))
+		}
+		return strings.Join(items, separator)
+	case map[string]interface{}:
+		items := make([]string, 0, len(v)*2)
+		for _, key := range getSortedKeys(v) {
+			if explode {
+				items = append(items, escape(key)+"="+escape(formatValue(v[key])))
+			} else {
+				items = append(items, escape(key), escape(formatValue(v[key])))
+			}
+		}
+		return strings.Join(items, separator)
+	}
+	return escape(formatValue(value))
+}
+
+// Format a primitive value as string, avoiding any exponent notation for numbers
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(value)
+}
+
+// Escape all but unreserved and reserved characters (allowReserved)
+func escapeUnreserved(value string) string {
+	escaped := strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+	for _, reserved := range []string{":", "/", "?", "#", "[", "]", "@", "!", "$", "'", "(", ")", "*", "+", ",", ";", "="} {
+		escaped = strings.Replace(escaped, url.QueryEscape(reserved), reserved, -1)
+	}
+	return escaped
+}
+
+// Header values are taken as is
+func noEscape(value string) string {
+	return value
+}
+
+// Get the keys of an object sorted for a stable serialization
+func getSortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
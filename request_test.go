@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"reflect"
+	"testing"
+)
+
+// The array and object values of the OpenAPI style examples
+var (
+	testColorArray  = []interface{}{"blue", "black", "brown"}
+	testColorObject = map[string]interface{}{"R": 100.0, "G": 200.0, "B": 150.0}
+)
+
+func TestSerializePathParameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		explode  bool
+		value    interface{}
+		expected string
+	}{
+		{"simple primitive", openapi3.SerializationSimple, false, "blue", "blue"},
+		{"simple array", openapi3.SerializationSimple, false, testColorArray, "blue,black,brown"},
+		{"simple array exploded", openapi3.SerializationSimple, true, testColorArray, "blue,black,brown"},
+		{"simple object", openapi3.SerializationSimple, false, testColorObject, "B,150,G,200,R,100"},
+		{"simple object exploded", openapi3.SerializationSimple, true, testColorObject, "B=150,G=200,R=100"},
+		{"simple escaped", openapi3.SerializationSimple, false, "a b/c", "a%20b%2Fc"},
+		{"label primitive", openapi3.SerializationLabel, false, "blue", ".blue"},
+		{"label array", openapi3.SerializationLabel, false, testColorArray, ".blue,black,brown"},
+		{"label array exploded", openapi3.SerializationLabel, true, testColorArray, ".blue.black.brown"},
+		{"label object", openapi3.SerializationLabel, false, testColorObject, ".B,150,G,200,R,100"},
+		{"label object exploded", openapi3.SerializationLabel, true, testColorObject, ".B=150.G=200.R=100"},
+		{"matrix primitive", openapi3.SerializationMatrix, false, "blue", ";color=blue"},
+		{"matrix array", openapi3.SerializationMatrix, false, testColorArray, ";color=blue,black,brown"},
+		{"matrix array exploded", openapi3.SerializationMatrix, true, testColorArray, ";color=blue;color=black;color=brown"},
+		{"matrix object", openapi3.SerializationMatrix, false, testColorObject, ";color=B,150,G,200,R,100"},
+		{"matrix object exploded", openapi3.SerializationMatrix, true, testColorObject, ";B=150;G=200;R=100"},
+	}
+	parameter := &openapi3.Parameter{Name: "color", In: openapi3.ParameterInPath}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := serializePathParameter(parameter, &openapi3.SerializationMethod{Style: test.style, Explode: test.explode}, test.value)
+			if actual != test.expected {
+				t.Errorf("'%s', expected '%s'", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestSerializeQueryParameter(t *testing.T) {
+	tests := []struct {
+		name          string
+		style         string
+		explode       bool
+		allowReserved bool
+		value         interface{}
+		expected      []string
+	}{
+		{"form primitive", openapi3.SerializationForm, true, false, "blue", []string{"color=blue"}},
+		{"form number", openapi3.SerializationForm, true, false, 1e21, []string{"color=1000000000000000000000"}},
+		{"form array", openapi3.SerializationForm, false, false, testColorArray, []string{"color=blue,black,brown"}},
+		{"form array exploded", openapi3.SerializationForm, true, false, testColorArray, []string{"color=blue", "color=black", "color=brown"}},
+		{"form object", openapi3.SerializationForm, false, false, testColorObject, []string{"color=B,150,G,200,R,100"}},
+		{"form object exploded", openapi3.SerializationForm, true, false, testColorObject, []string{"B=150", "G=200", "R=100"}},
+		{"form escaped", openapi3.SerializationForm, true, false, "a b&c/d", []string{"color=a+b%26c%2Fd"}},
+		{"form reserved allowed", openapi3.SerializationForm, true, true, "a b&c/d?", []string{"color=a%20b%26c/d?"}},
+		{"space delimited", openapi3.SerializationSpaceDelimited, false, false, testColorArray, []string{"color=blue%20black%20brown"}},
+		{"pipe delimited", openapi3.SerializationPipeDelimited, false, false, testColorArray, []string{"color=blue|black|brown"}},
+		{"deep object", openapi3.SerializationDeepObject, true, false, testColorObject, []string{"color%5BB%5D=150", "color%5BG%5D=200", "color%5BR%5D=100"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parameter := &openapi3.Parameter{Name: "color", In: openapi3.ParameterInQuery, AllowReserved: test.allowReserved}
+			actual := serializeQueryParameter(parameter, &openapi3.SerializationMethod{Style: test.style, Explode: test.explode}, test.value)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("%q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jedib0t/go-pretty/progress"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The loaded scenario, if any
+var scenario *Scenario
+
+// Operations to ping in order, e.g. creating an entity before reading it
+type Scenario struct {
+	Steps []*ScenarioStep `json:"steps"`
+}
+
+// An operation, by operationId or "METHOD /path", and the variables to extract from its response
+type ScenarioStep struct {
+	Operation string            `json:"operation"`
+	Extract   map[string]string `json:"extract,omitempty"`
+
+	operation *Operation
+}
+
+// Parse any given scenario file
+func parseScenario(operations []Operation) {
+	if scenarioFlag == nil || *scenarioFlag == "" {
+		return
+	}
+	var err error
+	scenario, err = loadScenario(*scenarioFlag, operations)
+	checkFatalError(err)
+}
+
+// Load a JSON or YAML scenario file and match its steps against the operations
+func loadScenario(input string, operations []Operation) (*Scenario, error) {
+	result := &Scenario{}
+	if err := loadConfig(input, result); err != nil {
+		return nil, err
+	}
+	if len(result.Steps) == 0 {
+		return nil, fmt.Errorf("the scenario '%s' has no steps", input)
+	}
+
+	for _, step := range result.Steps {
+		if step == nil {
+			return nil, fmt.Errorf("the scenario '%s' has an empty step", input)
+		}
+		step.operation = findOperation(operations, step.Operation)
+		if step.operation == nil {
+			return nil, fmt.Errorf("the scenario step '%s' matches no included operation", step.Operation)
+		}
+		if isFilteredPath(step.operation.Path) {
+			return nil, fmt.Errorf("the scenario step '%s' is excluded by the filter", step.Operation)
+		}
+	}
+	return result, nil
+}
+
+// Find an operation by its operationId or key, e.g. "GET /users/{id}"
+func findOperation(operations []Operation, reference string) *Operation {
+	for i, operation := range operations {
+		if operation.Operation.OperationID == reference && reference != "" {
+			return &operations[i]
+		}
+	}
+	parts := strings.SplitN(strings.TrimSpace(reference), " ", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	key := getOperationKey(parts[0], strings.TrimSpace(parts[1]))
+	for i, operation := range operations {
+		if getOperationKey(operation.Method, operation.Path) == key {
+			return &operations[i]
+		}
+	}
+	return nil
+}
+
+// Run the scenario once per worker, or repeatedly until the budget is exhausted, each with its own session and variables
+func loopScenario(budget *Budget, progressTracker *progress.Tracker) {
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < *workerFlag; worker++ {
+		waitGroup.Add(1)
+		go func(session *Session, random *rand.Rand) {
+			defer waitGroup.Done()
+			// Repeat the scenario until any budget is exhausted
+			for {
+				waitForStage(session)
+				runScenario(session, random, newVariables(), budget, progressTracker)
+				if budget.exhausted() {
+					break
+				}
+			}
+		}(sessions[worker], nextRand())
+	}
+	waitGroup.Wait()
+}
+
+// Ping all steps in order, feeding the variables of each response into the following requests
+func runScenario(session *Session, random *rand.Rand, variables *Variables, budget *Budget, progressTracker *progress.Tracker) {
+	for _, step := range scenario.Steps {
+		if !budget.take() {
+			return
+		}
+		operation := step.operation
+		contentType, body, bodyParsed := parseBody(random, operation.Operation)
+		request, requestParsed := parseRequest(random, operation.Path, operation.PathItem, operation.Operation, variables)
+		contentType, body = getLinkedBody(operation, variables, contentType, body)
+
+		ping := newPing(operation, request, contentType, body)
+		ping.Capture = true
+		pong := newPong(ping)
+		if bodyParsed && requestParsed {
+			send(session, pong)
+			step.extract(pong, variables)
+			applyLinks(operation, pong, variables)
+		} else {
+			// Count steps we cannot parse as errors, the following steps may depend on them
+			pong.Ping.Url = basePath + operation.Path
+			pong.Response = "[aPing] The request parameters or body could not be generated"
+			pong.StatusClass = StatusClassError
+			pong.ErrorCategory = ErrorCategoryRequest
+		}
+
+		collectPong(pong)
+		if progressTracker != nil {
+			progressTracker.Increment(1)
+		}
+		pingPool.Put(ping)
+	}
+}
+
+// Extract all variables of the step from the response, unresolved ones keep any previous value
+func (step *ScenarioStep) extract(pong *Pong, variables *Variables) {
+	if pong.StatusCode <= 0 {
+		return
+	}
+	// Sort the names for a stable evaluation order
+	names := make([]string, 0, len(step.Extract))
+	for name := range step.Extract {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if value, ok := evaluateExpression(step.Extract[name], pong); ok {
+			variables.set(name, value)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The loaded login request, if any
+var login *Login
+
+// The sessions of all workers
+var sessions []*Session
+
+// A request to log in each worker before the run, e.g. to receive a session cookie or token
+type Login struct {
+	Method      string            `json:"method,omitempty"`
+	Url         string            `json:"url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Body        interface{}       `json:"body,omitempty"`
+	// The headers to send with all pings of the worker, e.g. "Bearer {$response.body#/token}"
+	Session struct {
+		Headers map[string]string `json:"headers,omitempty"`
+	} `json:"session,omitempty"`
+}
+
+// The client of a worker, with its own cookie jar, and the headers of its login
+type Session struct {
+	worker  int
+	client  *http.Client
+	headers map[string]string
+}
+
+// Parse any given login request file
+func parseLogin() {
+	if loginFlag == nil || *loginFlag == "" {
+		return
+	}
+	login = &Login{}
+	checkFatalError(loadConfig(*loginFlag, login))
+	if login.Url == "" {
+		checkFatalError(fmt.Errorf("the login '%s' has no url", *loginFlag))
+	}
+}
+
+// Create a session per worker, each with its own cookie jar if requested, and log them in
+func parseSessions() {
+	sessions = make([]*Session, *workerFlag)
+	for worker := range sessions {
+		session := &Session{worker: worker, client: client}
+		if *jarFlag || login != nil {
+			jar, err := cookiejar.New(nil)
+			checkFatalError(err)
+			session.client = newClient(jar)
+		}
+		if login != nil {
+			if err := session.login(login); err != nil {
+				checkFatalError(fmt.Errorf("[aPing] The login of worker %d failed: %s", worker+1, err))
+			}
+		}
+		sessions[worker] = session
+	}
+}
+
+// Send the login request, keeping its cookies in the jar and resolving the session headers on its response
+func (session *Session) login(login *Login) error {
+	method := http.MethodPost
+	if login.Method != "" {
+		method = strings.ToUpper(login.Method)
+	}
+	loginUrl := login.Url
+	if _, ok := isValidUrl(loginUrl); !ok {
+		loginUrl = strings.TrimRight(basePath, "/") + "/" + strings.TrimLeft(loginUrl, "/")
+	}
+	contentType, body, err := getLoginBody(login)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, loginUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, value := range Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range login.Headers {
+		req.Header.Set(key, value)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	start := time.Now()
+	response, err := session.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("status %d after %s", response.StatusCode, time.Since(start))
+	}
+
+	// Resolve the session headers on the login exchange
+	pong := &Pong{
+		Ping:       Ping{Method: method, Url: loginUrl, Headers: login.Headers, ContentType: contentType, Body: body},
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       data,
+	}
+	session.headers = make(map[string]string, len(login.Session.Headers))
+	for key, expression := range login.Session.Headers {
+		value, ok := evaluateExpression(expression, pong)
+		if !ok {
+			return fmt.Errorf("the session header '%s' could not be resolved from '%s'", key, expression)
+		}
+		session.headers[key] = formatValue(value)
+	}
+	return nil
+}
+
+// Encode the login body as given text, form or JSON (default)
+func getLoginBody(login *Login) (string, []byte, error) {
+	switch body := login.Body.(type) {
+	case nil:
+		return login.ContentType, nil, nil
+	case string:
+		return login.ContentType, []byte(body), nil
+	case map[string]interface{}:
+		if strings.HasPrefix(login.ContentType, "application/x-www-form-urlencoded") {
+			form := url.Values{}
+			for _, key := range getSortedKeys(body) {
+				form.Set(key, formatValue(body[key]))
+			}
+			return login.ContentType, []byte(form.Encode()), nil
+		}
+	}
+	data, err := json.Marshal(login.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	contentType := login.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return contentType, data, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// The vendor extension of an operation declaring its SLO
+const SLOExtension = "x-aping-slo"
+
+// The prefix of the SLO column for breached operations
+const SLOBreached = "breached"
+
+// The SLOs by operation key, if any
+var slos = make(map[string]*SLO)
+
+// Latency budgets in milliseconds and an error budget in percent of an operation, all optional
+type SLO struct {
+	Mean      *float64 `json:"mean,omitempty"`
+	P50       *float64 `json:"p50,omitempty"`
+	P90       *float64 `json:"p90,omitempty"`
+	P95       *float64 `json:"p95,omitempty"`
+	P99       *float64 `json:"p99,omitempty"`
+	P999      *float64 `json:"p99.9,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	ErrorRate *float64 `json:"errorRate,omitempty"`
+}
+
+// Assign the SLOs of any given config file or the spec extensions to the pinged operations, not the filtered ones
+func parseSLOs(operations []Operation) {
+	input := ""
+	if sloFlag != nil {
+		input = *sloFlag
+	}
+	config, err := loadOperationConfig(input)
+	checkFatalError(err)
+
+	pinged := make([]Operation, 0, len(operations))
+	for _, operation := range operations {
+		if !isFilteredPath(operation.Path) {
+			pinged = append(pinged, operation)
+		}
+	}
+	for _, entry := range config.unmatched(pinged) {
+		log.Println(fmt.Sprintf("[aPing] The SLO of '%s' matches no pinged operation", entry))
+	}
+	for _, operation := range pinged {
+		slo := &SLO{}
+		found, err := config.unmarshal(operation, SLOExtension, slo)
+		checkFatalError(err)
+		if found {
+			slos[getOperationKey(operation.Method, operation.Path)] = slo
+		}
+	}
+}
+
+// Get all breaches of the SLO by the result, e.g. "p95 12.300 ms > 10.000 ms"
+func getSLOBreaches(slo *SLO, result Pongs) []string {
+	breaches := make([]string, 0)
+	if slo == nil {
+		return breaches
+	}
+	if result.Latency.Count > 0 {
+		for _, budget := range []struct {
+			name   string
+			actual float64
+			limit  *float64
+		}{
+			{"mean", result.Latency.Mean, slo.Mean},
+			{"p50", result.Latency.P50, slo.P50},
+			{"p90", result.Latency.P90, slo.P90},
+			{"p95", result.Latency.P95, slo.P95},
+			{"p99", result.Latency.P99, slo.P99},
+			{"p99.9", result.Latency.P999, slo.P999},
+			{"max", result.Latency.Max, slo.Max},
+		} {
+			if budget.limit != nil && budget.actual > *budget.limit {
+				breaches = append(breaches, fmt.Sprintf("%s %s ms > %s ms", budget.name, formatMS(budget.actual), formatMS(*budget.limit)))
+			}
+		}
+	}
+	if pings := result.Successes + result.Errors; slo.ErrorRate != nil && pings > 0 {
+		if errorRate := float64(result.Errors) / float64(pings) * 100; errorRate > *slo.ErrorRate {
+			breaches = append(breaches, fmt.Sprintf("errors %.2f%% > %.2f%%", errorRate, *slo.ErrorRate))
+		}
+	}
+	return breaches
+}
+
+// Format the SLO state of a result, "-" without an SLO
+func formatSLO(result Pongs) string {
+	if result.SLO == nil {
+		return "-"
+	}
+	if len(result.SLOBreaches) > 0 {
+		return SLOBreached + ": " + strings.Join(result.SLOBreaches, ", ")
+	}
+	return "OK"
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jedib0t/go-pretty/progress"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/jedib0t/go-pretty/text"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The stage targets to ramp
+const (
+	StageTargetWorkers = "workers"
+	StageTargetRate    = "rate"
+)
+
+// How often inactive workers and timelines without a rate check the stage again
+const stagePollInterval = 10 * time.Millisecond
+
+// Matching pattern for stages given on the command line, e.g. "2m:50,5m:50,1m:0"
+var regExInlineStagesPattern = regexp.MustCompile(`^\s*\d[\w.µ]*\s*:\s*[\d.]+\s*(,\s*\d[\w.µ]*\s*:\s*[\d.]+\s*)*$`)
+
+// The load profile of the run, if any
+var (
+	stages       []*Stage
+	stageTarget  string
+	stageStart   float64
+	stagesBegin  time.Time
+	stagesEnd    time.Time
+	StageResults []*StageResult
+)
+
+// A stage ramping the workers or rate linearly from the previous target to its own over its duration
+type Stage struct {
+	Duration string  `json:"duration"`
+	Target   float64 `json:"target"`
+	duration time.Duration
+	from     float64
+	tracker  *progress.Tracker
+}
+
+// The statistics of one stage, per operation and of all operations
+type StageResult struct {
+	Stage    int              `json:"stage"`
+	Ramp     string           `json:"ramp"`
+	From     float64          `json:"from"`
+	Target   float64          `json:"target"`
+	Duration string           `json:"duration"`
+	Results  map[string]Pongs `json:"results"`
+	Total    Pongs            `json:"total"`
+}
+
+// Parse the stages of a file or the command line, e.g. "2m:50,5m:50,1m:0" (duration:target).
+// They ramp the rate if given, else the workers, starting at the given value
+func parseStages() {
+	if stagesFlag == nil || *stagesFlag == "" {
+		return
+	}
+	// Anything but the inline form is a file, url or stdin
+	var err error
+	if regExInlineStagesPattern.MatchString(*stagesFlag) {
+		stages, err = parseInlineStages(*stagesFlag)
+	} else {
+		stages, err = loadStages(*stagesFlag)
+	}
+	checkFatalError(err)
+	if len(stages) == 0 {
+		checkFatalError(fmt.Errorf("[aPing] No stages given"))
+	}
+	if *durationFlag > 0 {
+		checkFatalError(fmt.Errorf("[aPing] The stages define the duration of the run"))
+	}
+
+	stageTarget, stageStart = StageTargetWorkers, float64(*workerFlag)
+	if *rateFlag > 0 {
+		stageTarget, stageStart = StageTargetRate, *rateFlag
+	}
+	from, total, maxWorkers := stageStart, time.Duration(0), *workerFlag
+	for i, stage := range stages {
+		if stage.duration, err = time.ParseDuration(stage.Duration); err != nil || stage.duration <= 0 {
+			checkFatalError(fmt.Errorf("[aPing] The duration '%s' of stage %d is invalid", stage.Duration, i+1))
+		}
+		if stage.Target < 0 {
+			checkFatalError(fmt.Errorf("[aPing] The target of stage %d cannot be negative", i+1))
+		}
+		stage.from = from
+		from = stage.Target
+		total += stage.duration
+		if workers := int(math.Ceil(stage.Target)); workers > maxWorkers {
+			maxWorkers = workers
+		}
+	}
+	// Create enough workers for the highest target, the inactive ones wait for their turn
+	if stageTarget == StageTargetWorkers {
+		*workerFlag = maxWorkers
+	}
+
+	// The stages are the duration of the run
+	if budget == nil {
+		budget = &Budget{}
+	}
+	budget.Duration = total
+}
+
+// Load the stages of a JSON/YAML file, e.g. {"stages": [{"duration": "2m", "target": 50}]}
+func loadStages(input string) ([]*Stage, error) {
+	config := struct {
+		Stages []*Stage `json:"stages"`
+	}{}
+	err := loadConfig(input, &config)
+	return config.Stages, err
+}
+
+// Parse the comma separated stages of the command line, e.g. "2m:50,5m:50,1m:0"
+func parseInlineStages(input string) ([]*Stage, error) {
+	result := make([]*Stage, 0)
+	for _, entry := range strings.Split(input, ",") {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("[aPing] The stage '%s' is not of the form duration:target", entry)
+		}
+		target, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("[aPing] The target of stage '%s' is invalid: %s", entry, err)
+		}
+		result = append(result, &Stage{Duration: strings.TrimSpace(parts[0]), Target: target})
+	}
+	return result, nil
+}
+
+// Start the stages now, tracking the progress of each
+func beginStages() {
+	if stages == nil {
+		return
+	}
+	stagesBegin = budget.start
+	StageResults = make([]*StageResult, len(stages))
+	for i, stage := range stages {
+		stage.tracker = &progress.Tracker{Message: fmt.Sprintf("Stage %d: %s", i+1, formatStage(stage)), Total: stage.duration.Milliseconds(), Units: unitsDuration}
+		progressWriter.AppendTracker(stage.tracker)
+		StageResults[i] = &StageResult{
+			Stage:    i + 1,
+			Ramp:     stageTarget,
+			From:     stage.from,
+			Target:   stage.Target,
+			Duration: stage.duration.String(),
+			Results:  make(map[string]Pongs),
+		}
+	}
+}
+
+// Update the elapsed time of each stage
+func trackStages(now time.Time) {
+	elapsed := now.Sub(stagesBegin)
+	for _, stage := range stages {
+		switch {
+		case elapsed >= stage.duration:
+			stage.tracker.MarkAsDone()
+		case elapsed > 0:
+			stage.tracker.SetValue(elapsed.Milliseconds())
+		}
+		elapsed -= stage.duration
+	}
+}
+
+// End the progress of all stages, the run may end before the last one
+func endStages() {
+	stagesEnd = time.Now()
+	for _, stage := range stages {
+		stage.tracker.MarkAsDone()
+	}
+}
+
+// Get the index of the stage at the given time, the last one afterwards
+func getStageIndex(at time.Time) int {
+	elapsed := at.Sub(stagesBegin)
+	for i, stage := range stages {
+		if elapsed < stage.duration {
+			return i
+		}
+		elapsed -= stage.duration
+	}
+	return len(stages) - 1
+}
+
+// Get the linearly ramped target at the given time, the last one afterwards
+func getStageValue(at time.Time) float64 {
+	elapsed := at.Sub(stagesBegin)
+	for _, stage := range stages {
+		if elapsed < stage.duration {
+			progress := float64(elapsed) / float64(stage.duration)
+			if progress < 0 {
+				progress = 0
+			}
+			return stage.from + (stage.Target-stage.from)*progress
+		}
+		elapsed -= stage.duration
+	}
+	return stages[len(stages)-1].Target
+}
+
+// Check if the stages ramp the rate
+func hasStageRate() bool {
+	return stages != nil && stageTarget == StageTargetRate
+}
+
+// Wait while the worker is not active in the current stage, e.g. during a ramp up. The warm-up runs before the stages
+func waitForStage(session *Session) {
+	if stages == nil || stageTarget != StageTargetWorkers || warmingUp {
+		return
+	}
+	for !budget.exhausted() && float64(session.worker) >= math.Round(getStageValue(time.Now())) {
+		time.Sleep(stagePollInterval)
+	}
+}
+
+// Record the pong in the statistics of the stage it started in, guarded by the results mutex
+func recordStagePong(pong *Pong) {
+	if stages == nil {
+		return
+	}
+	start := time.Now().Add(-pong.Time)
+	if !pong.Ping.intended.IsZero() {
+		start = pong.Ping.intended
+	}
+	result := StageResults[getStageIndex(start)]
+	key := getOperationKey(pong.Ping.Method, pong.Ping.Path)
+	result.Results[key] = recordPong(result.Results[key], pong)
+}
+
+// Summarize the latencies of all stages and operations
+func summarizeStages() {
+	for _, result := range StageResults {
+		total := Pongs{Histogram: newHistogram(), StatusCodes: make(map[int]int), ErrorCategories: make(map[string]int)}
+		for key, pongs := range result.Results {
+			pongs.Latency = pongs.Histogram.getLatency()
+			result.Results[key] = pongs
+			total.Histogram.merge(pongs.Histogram)
+			total.Successes += pongs.Successes
+			total.Errors += pongs.Errors
+			for statusCode, count := range pongs.StatusCodes {
+				total.StatusCodes[statusCode] += count
+			}
+			for errorCategory, count := range pongs.ErrorCategories {
+				total.ErrorCategories[errorCategory] += count
+			}
+		}
+		total.Latency = total.Histogram.getLatency()
+		result.Total = total
+	}
+}
+
+// Render the statistics of all operations per stage in the output format, to see where latency and errors inflect
+func renderStages(format string) string {
+	stageWriter := table.NewWriter()
+	stageWriter.SetTitle("Stages")
+	stageWriter.AppendHeader(table.Row{"Stage", "Ramp", "Duration", "Count", "Req/s", "Mean ms", "p95 ms", "p99 ms", "Max ms", "Status", "Error %"})
+	stageWriter.SetColumnConfigs([]table.ColumnConfig{
+		{Name: "Count", Align: text.AlignRight},
+		{Name: "Req/s", Align: text.AlignRight},
+		{Name: "Mean ms", Align: text.AlignRight},
+		{Name: "p95 ms", Align: text.AlignRight},
+		{Name: "p99 ms", Align: text.AlignRight},
+		{Name: "Max ms", Align: text.AlignRight},
+		{Name: "Error %", Align: text.AlignRight},
+	})
+	for i, result := range StageResults {
+		errorRate := 0.0
+		if pings := result.Total.Successes + result.Total.Errors; pings > 0 {
+			errorRate = float64(result.Total.Errors) / float64(pings) * 100
+		}
+		stageWriter.AppendRow(table.Row{
+			result.Stage,
+			formatStage(stages[i]),
+			result.Duration,
+			result.Total.Latency.Count,
+			formatRate(result.Total.Successes+result.Total.Errors, getStageRuntime(i)),
+			formatMS(result.Total.Latency.Mean),
+			formatMS(result.Total.Latency.P95),
+			formatMS(result.Total.Latency.P99),
+			formatMS(result.Total.Latency.Max),
+			formatOutcomes(result.Total),
+			fmt.Sprintf("%.2f", errorRate),
+		})
+	}
+	switch format {
+	case OutputHTML:
+		stageWriter.SetHTMLCSSClass("table table-striped table-hover table-responsive aping-stages-table")
+		return "<h5>Stages</h5>\n" + stageWriter.RenderHTML()
+	case OutputMarkdown:
+		return stageWriter.RenderMarkdown()
+	default:
+		return stageWriter.Render()
+	}
+}
+
+// Get the time the run actually spent in the stage, e.g. none if the requests ran out before
+func getStageRuntime(index int) time.Duration {
+	begin := stagesBegin
+	for _, stage := range stages[:index] {
+		begin = begin.Add(stage.duration)
+	}
+	end := begin.Add(stages[index].duration)
+	if !stagesEnd.IsZero() && stagesEnd.Before(end) {
+		end = stagesEnd
+	}
+	if end.Before(begin) {
+		return 0
+	}
+	return end.Sub(begin)
+}
+
+// Format the pings per second of the runtime, "-" without any runtime
+func formatRate(pings int, runtime time.Duration) string {
+	if runtime <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", float64(pings)/runtime.Seconds())
+}
+
+// Format the ramp of a stage, e.g. "1 → 50 workers" or "50 rate"
+func formatStage(stage *Stage) string {
+	if stage.from == stage.Target {
+		return fmt.Sprintf("%g %s", stage.Target, stageTarget)
+	}
+	return fmt.Sprintf("%g → %g %s", stage.from, stage.Target, stageTarget)
+}
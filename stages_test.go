@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInlineStages(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []*Stage
+		ok       bool
+	}{
+		{"single", "30s:10", []*Stage{{Duration: "30s", Target: 10}}, true},
+		{"multiple", "2m:50,5m:50,1m:0", []*Stage{{Duration: "2m", Target: 50}, {Duration: "5m", Target: 50}, {Duration: "1m", Target: 0}}, true},
+		{"spaces", " 1m30s : 2.5 , 10s:0 ", []*Stage{{Duration: "1m30s", Target: 2.5}, {Duration: "10s", Target: 0}}, true},
+		{"missing target", "30s", nil, false},
+		{"too many parts", "30s:1:2", nil, false},
+		{"invalid target", "30s:many", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := parseInlineStages(test.input)
+			if (err == nil) != test.ok || !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("%v (%v), expected %v", actual, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestInlineStagesPattern(t *testing.T) {
+	tests := []struct {
+		input  string
+		inline bool
+	}{
+		{"30s:10", true},
+		{"2m:50,5m:50,1m:0", true},
+		{"1m30s:2.5, 500ms:0", true},
+		{"stages.yaml", false},
+		{"./stages.json", false},
+		{"https://example.com/stages.yaml", false},
+		{"C:\\stages.yaml", false},
+		{"-", false},
+		{"30s:many", false},
+	}
+	for _, test := range tests {
+		if actual := regExInlineStagesPattern.MatchString(test.input); actual != test.inline {
+			t.Errorf("'%s' is inline: %t, expected %t", test.input, actual, test.inline)
+		}
+	}
+}
+
+func TestGetStageValue(t *testing.T) {
+	defer func(previous []*Stage, begin time.Time) {
+		stages, stagesBegin = previous, begin
+	}(stages, stagesBegin)
+
+	// Ramp from 0 to 10 in 10s, hold for 10s, ramp down to 2 in 4s
+	stagesBegin = time.Now()
+	stages = []*Stage{
+		{duration: 10 * time.Second, from: 0, Target: 10},
+		{duration: 10 * time.Second, from: 10, Target: 10},
+		{duration: 4 * time.Second, from: 10, Target: 2},
+	}
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		expected float64
+		index    int
+	}{
+		{"before", -time.Second, 0, 0},
+		{"start", 0, 0, 0},
+		{"ramp up", 2500 * time.Millisecond, 2.5, 0},
+		{"end of ramp up", 9 * time.Second, 9, 0},
+		{"hold", 10 * time.Second, 10, 1},
+		{"end of hold", 19 * time.Second, 10, 1},
+		{"ramp down", 22 * time.Second, 6, 2},
+		{"after", time.Minute, 2, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			at := stagesBegin.Add(test.elapsed)
+			if actual := getStageValue(at); math.Abs(actual-test.expected) > 1e-9 {
+				t.Errorf("value %g, expected %g", actual, test.expected)
+			}
+			if actual := getStageIndex(at); actual != test.index {
+				t.Errorf("index %d, expected %d", actual, test.index)
+			}
+		})
+	}
+}
+
+func TestGetStageRuntime(t *testing.T) {
+	defer func(previous []*Stage, begin time.Time, end time.Time) {
+		stages, stagesBegin, stagesEnd = previous, begin, end
+	}(stages, stagesBegin, stagesEnd)
+
+	stagesBegin = time.Now()
+	stages = []*Stage{{duration: 10 * time.Second}, {duration: 10 * time.Second}, {duration: 10 * time.Second}}
+	tests := []struct {
+		name     string
+		ended    time.Duration
+		expected []time.Duration
+	}{
+		{"completed", 30 * time.Second, []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second}},
+		{"late", 31 * time.Second, []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second}},
+		{"ended in second stage", 15 * time.Second, []time.Duration{10 * time.Second, 5 * time.Second, 0}},
+		{"ended at once", 0, []time.Duration{0, 0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stagesEnd = stagesBegin.Add(test.ended)
+			for i, expected := range test.expected {
+				if actual := getStageRuntime(i); actual != expected {
+					t.Errorf("stage %d ran %s, expected %s", i+1, actual, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderStages(t *testing.T) {
+	defer func(previous []*Stage, previousResults []*StageResult, begin time.Time, end time.Time) {
+		stages, StageResults, stagesBegin, stagesEnd = previous, previousResults, begin, end
+	}(stages, StageResults, stagesBegin, stagesEnd)
+
+	stagesBegin = time.Now()
+	stagesEnd = stagesBegin.Add(10 * time.Second)
+	stages = []*Stage{{Duration: "10s", Target: 5, duration: 10 * time.Second}}
+	StageResults = []*StageResult{{Stage: 1, Duration: "10s", Results: map[string]Pongs{}}}
+	summarizeStages()
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{OutputConsole, "Stages"},
+		{OutputHTML, "<h5>Stages</h5>"},
+		{OutputHTML, "aping-stages-table"},
+		{OutputMarkdown, "# Stages"},
+	}
+	for _, test := range tests {
+		if actual := renderStages(test.format); !strings.Contains(actual, test.expected) {
+			t.Errorf("the %s stages miss '%s': %s", test.format, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// The categories of failed requests
+const (
+	ErrorCategoryRequest = "request"
+	ErrorCategoryDNS     = "dns"
+	ErrorCategoryRefused = "connection refused"
+	ErrorCategoryReset   = "connection reset"
+	ErrorCategoryTLS     = "tls"
+	ErrorCategoryTimeout = "timeout"
+	ErrorCategoryOther   = "other"
+)
+
+// The status class of requests without any response
+const StatusClassError = "error"
+
+// Categorize a failed request by its cause
+func getErrorCategory(err error) string {
+	var dnsError *net.DNSError
+	var recordHeaderError tls.RecordHeaderError
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var certificateInvalidError x509.CertificateInvalidError
+	var netError net.Error
+
+	switch {
+	case errors.As(err, &dnsError):
+		return ErrorCategoryDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorCategoryRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorCategoryReset
+	case errors.As(err, &recordHeaderError), errors.As(err, &unknownAuthorityError),
+		errors.As(err, &hostnameError), errors.As(err, &certificateInvalidError):
+		return ErrorCategoryTLS
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netError) && netError.Timeout():
+		return ErrorCategoryTimeout
+	case strings.Contains(err.Error(), "tls:"):
+		return ErrorCategoryTLS
+	}
+	return ErrorCategoryOther
+}
+
+// Get the class of a status code, e.g. "2xx"
+func getStatusClass(statusCode int) string {
+	if statusCode <= 0 {
+		return StatusClassError
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
+
+// A ping is successful if it got a non-error response
+func isSuccess(pong *Pong) bool {
+	return pong.ErrorCategory == "" && pong.StatusCode > 0 && pong.StatusCode < 400
+}
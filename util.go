@@ -1,30 +1,82 @@
 package main
 
 import (
+	"encoding/binary"
+	"fmt"
 	"log"
 	"math/rand"
 	"net/url"
+	"sync"
 	"time"
 )
 
-// Seed
-var seededRand *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+// Seed, replaced by the given or a recorded random seed on start
+var seededRand *rand.Rand = newRand(time.Now().UnixNano())
+
+// Derive the random generator of a producer or worker from the seed, so their draws do not interleave.
+// They are created in a fixed order to replay the run
+func nextRand() *rand.Rand {
+	return rand.New(rand.NewSource(seededRand.Int63()))
+}
+
+// A random source safe for concurrent use, e.g. to derive the generators
+type lockedSource struct {
+	mutex  sync.Mutex
+	source rand.Source64
+}
+
+// Create a concurrency safe random generator of the given seed
+func newRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{source: rand.NewSource(seed).(rand.Source64)})
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.source.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.source.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.source.Seed(seed)
+}
 
 // String charset to randomly pick from
 const RandomStringCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 // Return a random string of the given length
-func getRandString(length int) string {
+func getRandString(random *rand.Rand, length int) string {
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = RandomStringCharset[seededRand.Intn(len(RandomStringCharset))]
+		b[i] = RandomStringCharset[random.Intn(len(RandomStringCharset))]
 	}
 	return string(b)
 }
 
-// Get the elapsed milliseconds from a given starting point
-func getElapsedTimeInMS(start int64) int64 {
-	return (time.Now().UnixNano() - start) / int64(time.Millisecond)
+// The fixed reference for random points in time, to be reproducible
+var referenceTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Return a random point in time within the year after the reference time
+func getRandTime(random *rand.Rand) time.Time {
+	return referenceTime.Add(time.Duration(random.Int63n(int64(365 * 24 * time.Hour)))).Truncate(time.Second)
+}
+
+// Return a random (version 4) UUID
+func getRandUUID(random *rand.Rand) string {
+	// Rand.Read keeps an unguarded state, take the bytes from two draws instead
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[0:8], random.Uint64())
+	binary.BigEndian.PutUint64(b[8:16], random.Uint64())
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
 // isValidUrl tests a string to determine if it is a well-structured url or not.
@@ -52,6 +104,21 @@ func contains(slice []string, val string) (int, bool) {
 	return -1, false
 }
 
+// Merge the additional headers into a copy of the given ones, if there are any
+func mergeHeaders(headers map[string]string, additional map[string]string) map[string]string {
+	if len(additional) == 0 {
+		return headers
+	}
+	merged := make(map[string]string, len(headers)+len(additional))
+	for key, value := range headers {
+		merged[key] = value
+	}
+	for key, value := range additional {
+		merged[key] = value
+	}
+	return merged
+}
+
 // If a critical error pops up, fail
 func checkFatalError(err error) {
 	if err != nil {
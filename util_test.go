@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNextRand(t *testing.T) {
+	defer func(previous *rand.Rand) {
+		seededRand = previous
+	}(seededRand)
+
+	draw := func() []int64 {
+		seededRand = newRand(42)
+		values := make([]int64, 0)
+		for i := 0; i < 3; i++ {
+			random := nextRand()
+			values = append(values, random.Int63(), random.Int63())
+		}
+		return values
+	}
+	first, second := draw(), draw()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("draw %d is %d, expected %d of the same seed", i, second[i], first[i])
+		}
+	}
+	// Every producer or worker draws its own values
+	if first[0] == first[2] || first[2] == first[4] {
+		t.Errorf("the generators draw the same values %v", first)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The kinds of response violations against the spec
+const (
+	ViolationStatus      = "status"
+	ViolationContentType = "content-type"
+	ViolationSchema      = "schema"
+)
+
+// Matching pattern for line breaks in violation messages
+var regExLineBreakPattern = regexp.MustCompile(`\s*\r?\n\s*`)
+
+// A violation of the response against the spec
+type Violation struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Validate the captured response of the pong against its operation, i.e. a declared status code, content type and schema
+func validatePong(pong *Pong) {
+	operation := pong.Ping.operation
+	if operation == nil || pong.StatusCode <= 0 {
+		return
+	}
+	// Operations without any declared response cannot be validated
+	if len(operation.Operation.Responses) == 0 {
+		return
+	}
+	response := getResponse(operation.Operation.Responses, pong.StatusCode)
+	if response == nil {
+		pong.Violations = append(pong.Violations, Violation{
+			Kind:    ViolationStatus,
+			Message: fmt.Sprintf("%d: status is not declared", pong.StatusCode),
+		})
+		return
+	}
+	// Validate against the matched response only, the filter does not know status code ranges
+	validated := *operation.Operation
+	validated.Responses = openapi3.Responses{strconv.Itoa(pong.StatusCode): response}
+
+	req, err := http.NewRequest(strings.ToUpper(pong.Ping.Method), pong.Ping.Url, nil)
+	if err != nil {
+		return
+	}
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request: req,
+			Route: &openapi3filter.Route{
+				Path:      operation.Path,
+				PathItem:  operation.PathItem,
+				Method:    operation.Method,
+				Operation: &validated,
+			},
+		},
+		Status:  pong.StatusCode,
+		Header:  pong.Header,
+		Body:    ioutil.NopCloser(bytes.NewReader(pong.Body)),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	if err = openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+		kind := ViolationSchema
+		if responseError, ok := err.(*openapi3filter.ResponseError); ok && strings.Contains(responseError.Reason, "Content-Type") {
+			kind = ViolationContentType
+		}
+		pong.Violations = append(pong.Violations, Violation{
+			Kind:    kind,
+			Message: fmt.Sprintf("%d: %s", pong.StatusCode, strings.TrimSpace(regExLineBreakPattern.ReplaceAllString(err.Error(), " "))),
+		})
+	}
+}
+
+// Get the declared response of a status code by its exact code, its range, e.g. "2XX", or the default
+func getResponse(responses openapi3.Responses, status int) *openapi3.ResponseRef {
+	if response := responses.Get(status); response != nil {
+		return response
+	}
+	for _, key := range []string{fmt.Sprintf("%dXX", status/100), fmt.Sprintf("%dxx", status/100)} {
+		if response, ok := responses[key]; ok {
+			return response
+		}
+	}
+	return responses.Default()
+}
+
+// Count the violations by kind and keep the first distinct messages of an operation
+func recordViolations(p *Pongs, violations []Violation) {
+	for _, violation := range violations {
+		if p.Violations == nil {
+			p.Violations = make(map[string]int)
+		}
+		p.Violations[violation.Kind]++
+		if _, found := contains(p.ViolationMessages, violation.Message); !found && len(p.ViolationMessages) < *violationsFlag {
+			p.ViolationMessages = append(p.ViolationMessages, violation.Message)
+		}
+	}
+}
+
+// Count all violations of an operation
+func countViolations(result Pongs) int {
+	count := 0
+	for _, violations := range result.Violations {
+		count += violations
+	}
+	return count
+}
+
+// Format the violation counts by kind and the first messages, e.g. "schema: 2" and "200: response body doesn't match..."
+func formatViolations(result Pongs) string {
+	kinds := make([]string, 0, len(result.Violations))
+	for kind := range result.Violations {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	counts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		counts[i] = fmt.Sprintf("%s: %d", kind, result.Violations[kind])
+	}
+	lines := append([]string{strings.Join(counts, ", ")}, result.ViolationMessages...)
+	return strings.Join(lines, "\r\n")
+}
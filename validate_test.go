@@ -0,0 +1,108 @@
+package main
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/http"
+	"testing"
+)
+
+// A spec with declared status codes, status code ranges, a default response and no responses at all
+const testValidateSpec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Pets", "version": "1.0"},
+	"paths": {
+		"/pets": {
+			"get": {
+				"responses": {
+					"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}}}}}}
+				}
+			}
+		},
+		"/owners": {
+			"get": {
+				"responses": {
+					"200": {"description": "OK"},
+					"default": {"description": "Error"}
+				}
+			}
+		},
+		"/orders": {
+			"get": {
+				"responses": {
+					"2XX": {"description": "OK", "content": {"application/json": {"schema": {"type": "array"}}}},
+					"4xx": {"description": "Client error"}
+				}
+			}
+		},
+		"/health": {
+			"get": {
+				"responses": {}
+			}
+		}
+	}
+}`
+
+// Validate a response of the operation and return the violation kinds
+func validateTestPong(swagger *openapi3.Swagger, path string, statusCode int, contentType string, body string) []string {
+	pong := &Pong{
+		Ping: Ping{
+			Method: http.MethodGet,
+			Path:   path,
+			Url:    "http://localhost" + path,
+			operation: &Operation{
+				Path:      path,
+				Method:    http.MethodGet,
+				PathItem:  swagger.Paths[path],
+				Operation: swagger.Paths[path].Get,
+			},
+		},
+		StatusCode: statusCode,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       []byte(body),
+	}
+	validatePong(pong)
+	kinds := make([]string, len(pong.Violations))
+	for i, violation := range pong.Violations {
+		kinds[i] = violation.Kind
+	}
+	return kinds
+}
+
+func TestValidatePong(t *testing.T) {
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData([]byte(testValidateSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		path        string
+		statusCode  int
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"valid", "/pets", 200, "application/json", `{"id": 1}`, ""},
+		{"schema", "/pets", 200, "application/json", `{"id": "one"}`, ViolationSchema},
+		{"missing property", "/pets", 200, "application/json", `{}`, ViolationSchema},
+		{"content type", "/pets", 200, "text/plain", `{"id": 1}`, ViolationContentType},
+		{"undeclared status", "/pets", 404, "application/json", `{}`, ViolationStatus},
+		{"without content", "/owners", 200, "text/plain", `anything`, ""},
+		{"default", "/owners", 500, "text/plain", `error`, ""},
+		{"range", "/orders", 201, "application/json", `[]`, ""},
+		{"range schema", "/orders", 200, "application/json", `{}`, ViolationSchema},
+		{"lowercase range", "/orders", 404, "text/plain", `not found`, ""},
+		{"outside ranges", "/orders", 500, "text/plain", `error`, ViolationStatus},
+		{"no declared responses", "/health", 500, "text/plain", `error`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kinds := validateTestPong(swagger, test.path, test.statusCode, test.contentType, test.body)
+			if test.expected == "" && len(kinds) > 0 {
+				t.Errorf("violations %v, expected none", kinds)
+			}
+			if test.expected != "" && (len(kinds) != 1 || kinds[0] != test.expected) {
+				t.Errorf("violations %v, expected %s", kinds, test.expected)
+			}
+		})
+	}
+}
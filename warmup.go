@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The warm-up rounds or duration, if any
+var (
+	warmupRounds   int
+	warmupDuration time.Duration
+	warmingUp      bool
+)
+
+// All collected warm-up pongs by operation key, excluded from the results
+var WarmupResults = make(map[string]Pongs)
+
+// Parse any given warm-up as rounds, e.g. "2", or duration, e.g. "30s"
+func parseWarmup() {
+	if warmupFlag == nil || *warmupFlag == "" {
+		return
+	}
+	if rounds, err := strconv.Atoi(*warmupFlag); err == nil && rounds >= 0 {
+		warmupRounds = rounds
+		return
+	}
+	duration, err := time.ParseDuration(*warmupFlag)
+	if err != nil || duration < 0 {
+		checkFatalError(fmt.Errorf("[aPing] The warm-up '%s' is neither a number of rounds nor a duration", *warmupFlag))
+	}
+	warmupDuration = duration
+}
+
+// Ping all operations (or the scenario) for the warm-up rounds or duration, discarding their samples.
+// Any warm-up errors are logged, to still notice a broken service
+func warmup(pings int, levels [][]*Operation) {
+	if warmupRounds <= 0 && warmupDuration <= 0 {
+		return
+	}
+	warmingUp = true
+	defer func() {
+		warmingUp = false
+	}()
+
+	if warmupDuration > 0 {
+		log.Println(fmt.Sprintf("[aPing] Warming up for %s", warmupDuration))
+		warmupBudget := &Budget{Duration: warmupDuration, start: time.Now()}
+		if scenario != nil {
+			loopScenario(warmupBudget, nil)
+		} else {
+			loop(pings, levels, warmupBudget, nil)
+		}
+	} else {
+		log.Println(fmt.Sprintf("[aPing] Warming up for %d rounds", warmupRounds))
+		for i := 0; i < warmupRounds; i++ {
+			if scenario != nil {
+				loopScenario(nil, nil)
+			} else {
+				loop(pings, levels, nil, nil)
+			}
+		}
+	}
+	logWarmup()
+}
+
+// Log the warm-up pings and the errors per operation
+func logWarmup() {
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+
+	successes, errors := 0, 0
+	keys := make([]string, 0, len(WarmupResults))
+	for key, result := range WarmupResults {
+		successes += result.Successes
+		errors += result.Errors
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	log.Println(fmt.Sprintf("[aPing] Warmed up with %d pings, %d errors", successes+errors, errors))
+	for _, key := range keys {
+		if result := WarmupResults[key]; result.Errors > 0 {
+			log.Println(fmt.Sprintf("[aPing] The warm-up of '%s' failed %d times: %s", key, result.Errors, formatOutcomes(result)))
+		}
+	}
+}
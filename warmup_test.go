@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWarmup(t *testing.T) {
+	defer func(previousFlag string, previousRounds int, previousDuration time.Duration) {
+		*warmupFlag, warmupRounds, warmupDuration = previousFlag, previousRounds, previousDuration
+	}(*warmupFlag, warmupRounds, warmupDuration)
+
+	tests := []struct {
+		name     string
+		flag     string
+		rounds   int
+		duration time.Duration
+	}{
+		{"none", "", 0, 0},
+		{"rounds", "2", 2, 0},
+		{"zero rounds", "0", 0, 0},
+		{"duration", "30s", 0, 30 * time.Second},
+		{"compound duration", "1m30s", 0, 90 * time.Second},
+		{"fractional duration", "1.5s", 0, 1500 * time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			*warmupFlag, warmupRounds, warmupDuration = test.flag, 0, 0
+			parseWarmup()
+			if warmupRounds != test.rounds || warmupDuration != test.duration {
+				t.Errorf("%d rounds and %s, expected %d rounds and %s", warmupRounds, warmupDuration, test.rounds, test.duration)
+			}
+		})
+	}
+}
+
+func TestCollectPongWarmingUp(t *testing.T) {
+	defer func(previousResults map[string]Pongs, previousWarmupResults map[string]Pongs) {
+		Results, WarmupResults, warmingUp = previousResults, previousWarmupResults, false
+	}(Results, WarmupResults)
+
+	tests := []struct {
+		name      string
+		warmingUp bool
+		results   int
+		warmup    int
+	}{
+		{"warming up", true, 0, 1},
+		{"running", false, 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Results, WarmupResults, warmingUp = make(map[string]Pongs), make(map[string]Pongs), test.warmingUp
+			collectPong(&Pong{Ping: Ping{Method: "GET", Path: "/pets"}, StatusCode: 200, Time: time.Millisecond})
+			if len(Results) != test.results || len(WarmupResults) != test.warmup {
+				t.Errorf("%d results and %d warm-up results, expected %d and %d", len(Results), len(WarmupResults), test.results, test.warmup)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The vendor extension of an operation declaring its weight in the traffic mix
+const WeightExtension = "x-aping-weight"
+
+// The weights by operation key, if any are given
+var weights = make(map[string]float64)
+
+// A traffic mix picking operations by their weight
+type Mix struct {
+	operations []*Operation
+	cumulative []float64
+	total      float64
+}
+
+// Assign the weights of any given config file or the spec extensions to the operations, 1 by default
+func parseWeights(operations []Operation) {
+	input := ""
+	if weightsFlag != nil {
+		input = *weightsFlag
+	}
+	config, err := loadOperationConfig(input)
+	checkFatalError(err)
+
+	found := false
+	for _, operation := range operations {
+		weight := 1.0
+		ok, err := config.unmarshal(operation, WeightExtension, &weight)
+		checkFatalError(err)
+		if weight < 0 {
+			checkFatalError(fmt.Errorf("[aPing] The weight of '%s' cannot be negative", getOperationKey(operation.Method, operation.Path)))
+		}
+		found = found || ok
+		weights[getOperationKey(operation.Method, operation.Path)] = weight
+	}
+	// Without any weight given, the rounds go on as before
+	if !found {
+		weights = make(map[string]float64)
+		return
+	}
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		checkFatalError(fmt.Errorf("[aPing] The weights of all operations are 0"))
+	}
+}
+
+// Check if the operations are picked by weight, in runs with the given duration/count budget only.
+// Rounds without a budget, e.g. of the warm-up, go through all operations
+func hasWeights(budget *Budget) bool {
+	return budget != nil && len(weights) > 0
+}
+
+// Create the traffic mix of the operations with a positive weight
+func newMix(operations []*Operation) *Mix {
+	mix := &Mix{}
+	for _, operation := range operations {
+		if weight := weights[getOperationKey(operation.Method, operation.Path)]; weight > 0 {
+			mix.total += weight
+			mix.operations = append(mix.operations, operation)
+			mix.cumulative = append(mix.cumulative, mix.total)
+		}
+	}
+	return mix
+}
+
+// Pick a random operation by weight, seeded to replay the run
+func (mix *Mix) pick(random *rand.Rand) *Operation {
+	value := random.Float64() * mix.total
+	index := sort.Search(len(mix.cumulative), func(i int) bool {
+		return mix.cumulative[i] > value
+	})
+	if index >= len(mix.operations) {
+		index = len(mix.operations) - 1
+	}
+	return mix.operations[index]
+}
+
+// Queue operations picked by weight until the budget is exhausted, at the arrivals of the timeline, if any
+func scheduleMix(mix *Mix, random *rand.Rand, timeline *Timeline, budget *Budget, variables *Variables, jobs chan<- *Ping, waitGroup *sync.WaitGroup) {
+	if len(mix.operations) == 0 {
+		return
+	}
+	for {
+		// Picks of the size of the mix form a round
+		queued := 0
+		for i := 0; i < len(mix.operations); i++ {
+			operation := mix.pick(random)
+			// Skip routes with request bodies we cannot generate
+			contentType, body, parsed := parseBody(random, operation.Operation)
+			if !parsed {
+				continue
+			}
+			// Skip routes we cannot parse (yet)
+			request, parsed := parseRequest(random, operation.Path, operation.PathItem, operation.Operation, variables)
+			if !parsed {
+				continue
+			}
+			var intended time.Time
+			if timeline != nil {
+				intended = timeline.wait()
+			} else {
+				intended = time.Now()
+			}
+			if !budget.takeAt(intended) {
+				return
+			}
+			contentType, body = getLinkedBody(operation, variables, contentType, body)
+			ping := newPing(operation, request, contentType, body)
+			if timeline != nil {
+				ping.intended = intended
+			}
+			if hasLinks(operation) {
+				ping.Capture = true
+				ping.variables = variables
+			}
+			// Fire
+			waitGroup.Add(1)
+			jobs <- ping
+			queued++
+		}
+		// Stop if nothing could be pinged (anymore)
+		if queued == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// The picks to sample the distribution of a mix with
+const mixTestPicks = 100000
+
+func TestMixPick(t *testing.T) {
+	defer func(previous map[string]float64) {
+		weights = previous
+	}(weights)
+
+	tests := []struct {
+		name    string
+		weights map[string]float64
+	}{
+		{"equal", map[string]float64{"/a": 1, "/b": 1}},
+		{"skewed", map[string]float64{"/a": 6, "/b": 3, "/c": 1}},
+		{"fractions", map[string]float64{"/a": 0.25, "/b": 0.75}},
+		{"zero excluded", map[string]float64{"/a": 2, "/b": 0, "/c": 2}},
+		{"single", map[string]float64{"/a": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			weights = make(map[string]float64)
+			operations := make([]*Operation, 0, len(test.weights))
+			total := 0.0
+			for path, weight := range test.weights {
+				operations = append(operations, &Operation{Path: path, Method: "GET", Operation: &openapi3.Operation{}})
+				weights[getOperationKey("GET", path)] = weight
+				total += weight
+			}
+			mix := newMix(operations)
+
+			random := rand.New(rand.NewSource(1))
+			picks := make(map[string]int)
+			for i := 0; i < mixTestPicks; i++ {
+				picks[mix.pick(random).Path]++
+			}
+			for path, weight := range test.weights {
+				expected := weight / total
+				actual := float64(picks[path]) / mixTestPicks
+				if weight == 0 && picks[path] > 0 {
+					t.Errorf("'%s' without weight was picked %d times", path, picks[path])
+				}
+				if math.Abs(actual-expected) > 0.01 {
+					t.Errorf("'%s' was picked %.3f of the time, expected %.3f", path, actual, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestNewMixEmpty(t *testing.T) {
+	defer func(previous map[string]float64) {
+		weights = previous
+	}(weights)
+
+	weights = map[string]float64{"GET /a": 0}
+	if mix := newMix([]*Operation{{Path: "/a", Method: "GET"}}); len(mix.operations) != 0 {
+		t.Errorf("the mix has %d operations without weight", len(mix.operations))
+	}
+}